@@ -46,7 +46,7 @@ func NewAPI(registry *session.Registry, logger *slog.Logger, authentication Auth
 	a.RegisterHandler(packet.IDKick, func(_ *Client, pk packet.Packet) {
 		username := pk.(*packet.Kick).Username
 		reason := pk.(*packet.Kick).Reason
-		if s := a.registry.GetSessionByUsername(username); s != nil {
+		if s := a.registry.GetSessionByName(username); s != nil {
 			s.Disconnect(reason)
 		} else {
 			a.logger.Debug("tried to disconnect an unknown player", "username", username, "reason", reason)
@@ -55,7 +55,7 @@ func NewAPI(registry *session.Registry, logger *slog.Logger, authentication Auth
 	a.RegisterHandler(packet.IDTransfer, func(_ *Client, pk packet.Packet) {
 		username := pk.(*packet.Transfer).Username
 		addr := pk.(*packet.Transfer).Addr
-		if s := a.registry.GetSessionByUsername(username); s != nil {
+		if s := a.registry.GetSessionByName(username); s != nil {
 			if err := s.Transfer(addr); err != nil {
 				a.logger.Error("failed to transfer player", "username", username, "addr", addr, "err", err)
 			}
@@ -2,10 +2,19 @@ package protocol
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 )
 
+// MaxFrameSize is the maximum length of a packet read by a Reader. Packets exceeding it are discarded from the
+// underlying io.Reader without being buffered.
+const MaxFrameSize = 1024 * 1024 * 32
+
+// ErrFrameTooLarge is returned by Reader.ReadPacket when the length of a packet exceeds MaxFrameSize. The packet
+// is discarded entirely, meaning that the Reader remains usable.
+var ErrFrameTooLarge = errors.New("frame exceeds maximum size")
+
 // Reader is used for reading packets from an io.Reader.
 type Reader struct {
 	// r is the underlying io.Reader used for reading data.
@@ -26,6 +35,13 @@ func (r *Reader) ReadPacket() ([]byte, error) {
 		return nil, fmt.Errorf("failed to read packet length: %w", err)
 	}
 
+	if length > MaxFrameSize {
+		if _, err := io.CopyN(io.Discard, r.r, int64(length)); err != nil {
+			return nil, fmt.Errorf("failed to discard packet data: %w", err)
+		}
+		return nil, fmt.Errorf("%w: %v bytes", ErrFrameTooLarge, length)
+	}
+
 	pk := make([]byte, length)
 	if _, err := io.ReadFull(r.r, pk); err != nil {
 		return nil, fmt.Errorf("failed to read packet data: %w", err)
@@ -0,0 +1,81 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+)
+
+func frame(length uint32, data []byte) []byte {
+	return append(binary.BigEndian.AppendUint32(nil, length), data...)
+}
+
+func TestReaderReadPacket(t *testing.T) {
+	tests := []struct {
+		name    string
+		stream  []byte
+		want    []byte
+		wantErr error
+	}{
+		{
+			name:   "valid frame",
+			stream: frame(3, []byte{1, 2, 3}),
+			want:   []byte{1, 2, 3},
+		},
+		{
+			name:   "empty frame",
+			stream: frame(0, nil),
+			want:   []byte{},
+		},
+		{
+			name:    "oversized frame",
+			stream:  frame(MaxFrameSize+1, make([]byte, MaxFrameSize+1)),
+			wantErr: ErrFrameTooLarge,
+		},
+		{
+			name:    "oversized frame truncated",
+			stream:  frame(MaxFrameSize+1, []byte{1}),
+			wantErr: io.EOF,
+		},
+		{
+			name:    "truncated frame",
+			stream:  frame(3, []byte{1}),
+			wantErr: io.ErrUnexpectedEOF,
+		},
+		{
+			name:    "truncated length",
+			stream:  []byte{0, 0},
+			wantErr: io.ErrUnexpectedEOF,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pk, err := NewReader(bytes.NewReader(tt.stream)).ReadPacket()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ReadPacket() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && !bytes.Equal(pk, tt.want) {
+				t.Fatalf("ReadPacket() = %v, want %v", pk, tt.want)
+			}
+		})
+	}
+}
+
+func TestReaderSkipsOversizedFrame(t *testing.T) {
+	stream := frame(MaxFrameSize+1, make([]byte, MaxFrameSize+1))
+	stream = append(stream, frame(2, []byte{4, 5})...)
+	r := NewReader(bytes.NewReader(stream))
+	if _, err := r.ReadPacket(); !errors.Is(err, ErrFrameTooLarge) {
+		t.Fatalf("ReadPacket() error = %v, want %v", err, ErrFrameTooLarge)
+	}
+
+	pk, err := r.ReadPacket()
+	if err != nil {
+		t.Fatalf("ReadPacket() error = %v", err)
+	}
+	if !bytes.Equal(pk, []byte{4, 5}) {
+		t.Fatalf("ReadPacket() = %v, want %v", pk, []byte{4, 5})
+	}
+}
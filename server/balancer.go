@@ -0,0 +1,111 @@
+package server
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/sandertv/gophertunnel/minecraft"
+)
+
+// ErrNoServers is returned by discoveries when there are no servers to choose from.
+var ErrNoServers = errors.New("no servers available")
+
+// Strategy defines an interface for selecting a server for a player out of a list of candidates.
+type Strategy interface {
+	// Select selects one of the provided servers, which are never empty, for the player's connection.
+	Select(conn *minecraft.Conn, servers []string) (string, error)
+}
+
+// LoadBalancedDiscovery implements the Discovery interface, balancing players across a list of servers using a Strategy.
+type LoadBalancedDiscovery struct {
+	servers         []string
+	fallbackServers []string
+	strategy        Strategy
+}
+
+// NewLoadBalancedDiscovery creates a new LoadBalancedDiscovery selecting servers using the provided strategy.
+// Fallback servers are selected out of the provided fallback servers, or out of the primary servers if there are none.
+func NewLoadBalancedDiscovery(servers []string, fallbackServers []string, strategy Strategy) *LoadBalancedDiscovery {
+	return &LoadBalancedDiscovery{
+		servers:         servers,
+		fallbackServers: fallbackServers,
+		strategy:        strategy,
+	}
+}
+
+// Discover ...
+func (d *LoadBalancedDiscovery) Discover(conn *minecraft.Conn) (string, error) {
+	if len(d.servers) == 0 {
+		return "", ErrNoServers
+	}
+	return d.strategy.Select(conn, d.servers)
+}
+
+// DiscoverFallback ...
+func (d *LoadBalancedDiscovery) DiscoverFallback(conn *minecraft.Conn) (string, error) {
+	if len(d.fallbackServers) == 0 {
+		return d.Discover(conn)
+	}
+	return d.strategy.Select(conn, d.fallbackServers)
+}
+
+// RoundRobin implements the Strategy interface, selecting servers in turn.
+type RoundRobin struct {
+	next atomic.Uint64
+}
+
+// NewRoundRobin creates a new RoundRobin strategy.
+func NewRoundRobin() *RoundRobin {
+	return &RoundRobin{}
+}
+
+// Select ...
+func (r *RoundRobin) Select(_ *minecraft.Conn, servers []string) (string, error) {
+	return servers[(r.next.Add(1)-1)%uint64(len(servers))], nil
+}
+
+// LeastConnections implements the Strategy interface, selecting the server with the least players. The players of
+// each server are counted by the provided function, usually backed by the sessions of the proxy.
+type LeastConnections struct {
+	count func(addr string) int
+}
+
+// NewLeastConnections creates a new LeastConnections strategy counting the players of a server using the provided function.
+func NewLeastConnections(count func(addr string) int) *LeastConnections {
+	return &LeastConnections{count: count}
+}
+
+// Select ...
+func (l *LeastConnections) Select(_ *minecraft.Conn, servers []string) (string, error) {
+	selected, least := servers[0], l.count(servers[0])
+	for _, server := range servers[1:] {
+		if count := l.count(server); count < least {
+			selected, least = server, count
+		}
+	}
+	return selected, nil
+}
+
+// LowestLatency implements the Strategy interface, selecting the server with the lowest latency. The latency of each
+// server is provided by a function, such as HealthChecked.Latency, which reports false for servers with an unknown
+// latency. Servers with an unknown latency are only selected if no latency is known for any server.
+type LowestLatency struct {
+	latency func(addr string) (time.Duration, bool)
+}
+
+// NewLowestLatency creates a new LowestLatency strategy using the provided function to get the latency of servers.
+func NewLowestLatency(latency func(addr string) (time.Duration, bool)) *LowestLatency {
+	return &LowestLatency{latency: latency}
+}
+
+// Select ...
+func (l *LowestLatency) Select(_ *minecraft.Conn, servers []string) (string, error) {
+	selected, lowest, found := servers[0], time.Duration(0), false
+	for _, server := range servers {
+		if latency, ok := l.latency(server); ok && (!found || latency < lowest) {
+			selected, lowest, found = server, latency, true
+		}
+	}
+	return selected, nil
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRoundRobin(t *testing.T) {
+	tests := []struct {
+		name    string
+		servers []string
+		want    []string
+	}{
+		{name: "single", servers: []string{"a"}, want: []string{"a", "a", "a"}},
+		{name: "in turn", servers: []string{"a", "b", "c"}, want: []string{"a", "b", "c", "a", "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRoundRobin()
+			var got []string
+			for range tt.want {
+				server, _ := r.Select(nil, tt.servers)
+				got = append(got, server)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("Select() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLeastConnections(t *testing.T) {
+	tests := []struct {
+		name    string
+		counts  map[string]int
+		servers []string
+		want    string
+	}{
+		{name: "single", counts: map[string]int{"a": 10}, servers: []string{"a"}, want: "a"},
+		{name: "least", counts: map[string]int{"a": 10, "b": 2, "c": 5}, servers: []string{"a", "b", "c"}, want: "b"},
+		{name: "tie", counts: map[string]int{"a": 10, "b": 2, "c": 2}, servers: []string{"a", "b", "c"}, want: "b"},
+		{name: "empty servers", counts: map[string]int{"a": 1}, servers: []string{"a", "b", "c"}, want: "b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLeastConnections(func(addr string) int { return tt.counts[addr] })
+			if got, _ := l.Select(nil, tt.servers); got != tt.want {
+				t.Fatalf("Select() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLowestLatency(t *testing.T) {
+	tests := []struct {
+		name      string
+		latencies map[string]time.Duration
+		servers   []string
+		want      string
+	}{
+		{name: "lowest", latencies: map[string]time.Duration{"a": 30, "b": 10, "c": 20}, servers: []string{"a", "b", "c"}, want: "b"},
+		{name: "tie", latencies: map[string]time.Duration{"a": 30, "b": 10, "c": 10}, servers: []string{"a", "b", "c"}, want: "b"},
+		{name: "unknown skipped", latencies: map[string]time.Duration{"b": 30}, servers: []string{"a", "b", "c"}, want: "b"},
+		{name: "zero latency", latencies: map[string]time.Duration{"a": 30, "c": 0}, servers: []string{"a", "b", "c"}, want: "c"},
+		{name: "all unknown", servers: []string{"a", "b", "c"}, want: "a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLowestLatency(func(addr string) (time.Duration, bool) {
+				latency, ok := tt.latencies[addr]
+				return latency, ok
+			})
+			if got, _ := l.Select(nil, tt.servers); got != tt.want {
+				t.Fatalf("Select() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadBalancedDiscovery(t *testing.T) {
+	tests := []struct {
+		name         string
+		servers      []string
+		fallback     []string
+		want         string
+		wantFallback string
+		wantErr      error
+	}{
+		{name: "servers", servers: []string{"a", "b"}, fallback: []string{"c"}, want: "a", wantFallback: "c"},
+		{name: "no fallback servers", servers: []string{"a", "b"}, want: "a", wantFallback: "a"},
+		{name: "no servers", fallback: []string{"c"}, wantErr: ErrNoServers, wantFallback: "c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// A fresh strategy is used for each discovery, so that both select the first of their servers.
+			server, err := NewLoadBalancedDiscovery(tt.servers, tt.fallback, NewRoundRobin()).Discover(nil)
+			if server != tt.want || !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Discover() = %q, %v, want %q, %v", server, err, tt.want, tt.wantErr)
+			}
+			fallback, err := NewLoadBalancedDiscovery(tt.servers, tt.fallback, NewRoundRobin()).DiscoverFallback(nil)
+			if fallback != tt.wantFallback || err != nil {
+				t.Fatalf("DiscoverFallback() = %q, %v, want %q", fallback, err, tt.wantFallback)
+			}
+		})
+	}
+}
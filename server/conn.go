@@ -8,8 +8,10 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"maps"
 	"slices"
 	"sync"
+	"sync/atomic"
 
 	"github.com/cooldogedev/spectrum/protocol"
 	spectrumpacket "github.com/cooldogedev/spectrum/server/packet"
@@ -23,6 +25,23 @@ const (
 	packetDecodeNotNeeded
 )
 
+// ErrMalformedPacket is returned by Conn.ReadPacket when a packet sent by the server could not be decoded.
+// The packet is read from the connection entirely, meaning that the connection remains usable and the
+// packet may be skipped.
+var ErrMalformedPacket = errors.New("malformed packet")
+
+const (
+	// TapDirectionRead is the direction of frames read from the server.
+	TapDirectionRead = iota
+	// TapDirectionWrite is the direction of frames written to the server.
+	TapDirectionWrite
+)
+
+// RawTap is a function observing the raw frames of a Conn before they are decoded or after they are encoded.
+// The frame passed is a copy owned by the tap, it includes the decode marker byte for frames read from the server
+// and is snappy compressed in both directions.
+type RawTap func(direction int, frame []byte)
+
 var bufferPool = sync.Pool{
 	New: func() any {
 		return bytes.NewBuffer(make([]byte, 0, 256))
@@ -64,6 +83,7 @@ type Conn struct {
 	expectedIds     []uint32
 
 	onConnect func(err error)
+	tap       atomic.Pointer[RawTap]
 
 	connected chan struct{}
 	spawned   chan struct{}
@@ -72,7 +92,9 @@ type Conn struct {
 
 // NewConn creates a new Conn instance using the provided io.ReadWriteCloser.
 // It is used for reading and writing packets to the underlying connection.
-func NewConn(conn io.ReadWriteCloser, client *minecraft.Conn, logger *slog.Logger, syncProtocol bool, cache []byte) *Conn {
+// The packets in the provided pool, which may be nil, are decoded in addition to the protocol's packets,
+// allowing custom server packets to be registered per connection.
+func NewConn(conn io.ReadWriteCloser, client *minecraft.Conn, logger *slog.Logger, syncProtocol bool, cache []byte, pool packet.Pool) *Conn {
 	var proto minecraft.Protocol
 	if syncProtocol {
 		proto = client.Proto()
@@ -80,6 +102,12 @@ func NewConn(conn io.ReadWriteCloser, client *minecraft.Conn, logger *slog.Logge
 		proto = minecraft.DefaultProtocol
 	}
 
+	packets := proto.Packets(false)
+	if len(pool) > 0 {
+		packets = maps.Clone(packets)
+		maps.Copy(packets, pool)
+	}
+
 	c := &Conn{
 		conn:   conn,
 		client: client,
@@ -92,7 +120,7 @@ func NewConn(conn io.ReadWriteCloser, client *minecraft.Conn, logger *slog.Logge
 		cache:        cache,
 
 		protocol: proto,
-		pool:     proto.Packets(false),
+		pool:     packets,
 
 		connected: make(chan struct{}),
 		spawned:   make(chan struct{}),
@@ -142,25 +170,26 @@ func (c *Conn) WritePacket(pk packet.Packet) error {
 	default:
 	}
 
-	buf := bufferPool.Get().(*bytes.Buffer)
-	header := headerPool.Get().(*packet.Header)
-	defer func() {
-		buf.Reset()
-		bufferPool.Put(buf)
-		headerPool.Put(header)
-	}()
-
-	header.PacketID = pk.ID()
-	if err := header.Write(buf); err != nil {
+	payload, err := encodePacket(pk, c.protocol, c.shieldID)
+	if err != nil {
 		return err
 	}
-	pk.Marshal(c.protocol.NewWriter(buf, c.shieldID))
-	return c.writer.Write(snappy.Encode(nil, buf.Bytes()))
+	return c.write(payload)
 }
 
 // Write writes provided byte slice to the underlying connection.
 func (c *Conn) Write(p []byte) error {
-	return c.writer.Write(snappy.Encode(nil, p))
+	return c.write(snappy.Encode(nil, p))
+}
+
+// SetRawTap sets the tap observing the raw frames read from and written to the connection. Passing nil
+// removes the current tap.
+func (c *Conn) SetRawTap(tap RawTap) {
+	if tap == nil {
+		c.tap.Store(nil)
+		return
+	}
+	c.tap.Store(&tap)
 }
 
 // DoConnect sends a ConnectionRequest packet to initiate the connection sequence.
@@ -265,22 +294,63 @@ func (c *Conn) CloseWithError(err error) {
 }
 
 // read reads a packet from the connection, handling decompression and decoding as necessary.
-// Packets are prefixed with a special byte (packetDecodeNeeded or packetDecodeNotNeeded) indicating
-// the decoding necessity. If decode is false and the packet does not require decoding,
-// it returns the raw decompressed payload.
 func (c *Conn) read() (pk any, err error) {
 	payload, err := c.reader.ReadPacket()
-	if err != nil {
+	if errors.Is(err, protocol.ErrFrameTooLarge) {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedPacket, err)
+	} else if err != nil {
+		return nil, err
+	}
+	c.handleTap(TapDirectionRead, payload)
+	return decodePacket(payload, c.pool, c.protocol, c.shieldID)
+}
+
+// write writes the compressed payload to the underlying connection.
+func (c *Conn) write(payload []byte) error {
+	c.handleTap(TapDirectionWrite, payload)
+	return c.writer.Write(payload)
+}
+
+// handleTap passes a copy of the frame to the connection's tap, if one is set.
+func (c *Conn) handleTap(direction int, frame []byte) {
+	if tap := c.tap.Load(); tap != nil {
+		(*tap)(direction, bytes.Clone(frame))
+	}
+}
+
+// encodePacket encodes the packet using the provided protocol and compresses it, ready to be written to a server.
+func encodePacket(pk packet.Packet, proto minecraft.Protocol, shieldID int32) ([]byte, error) {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	header := headerPool.Get().(*packet.Header)
+	defer func() {
+		buf.Reset()
+		bufferPool.Put(buf)
+		headerPool.Put(header)
+	}()
+
+	header.PacketID = pk.ID()
+	if err := header.Write(buf); err != nil {
 		return nil, err
 	}
+	pk.Marshal(proto.NewWriter(buf, shieldID))
+	return snappy.Encode(nil, buf.Bytes()), nil
+}
+
+// decodePacket decompresses and decodes a payload read from a server.
+// Packets are prefixed with a special byte (packetDecodeNeeded or packetDecodeNotNeeded) indicating
+// the decoding necessity. If the packet does not require decoding, it returns the raw decompressed payload.
+func decodePacket(payload []byte, pool packet.Pool, proto minecraft.Protocol, shieldID int32) (pk any, err error) {
+	if len(payload) == 0 {
+		return nil, fmt.Errorf("%w: empty payload", ErrMalformedPacket)
+	}
 
 	if payload[0] != packetDecodeNeeded && payload[0] != packetDecodeNotNeeded {
-		return nil, fmt.Errorf("unknown decode byte marker %v", payload[0])
+		return nil, fmt.Errorf("%w: unknown decode byte marker %v", ErrMalformedPacket, payload[0])
 	}
 
 	decompressed, err := snappy.Decode(nil, payload[1:])
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %w", ErrMalformedPacket, err)
 	}
 
 	if payload[0] == packetDecodeNotNeeded {
@@ -292,19 +362,19 @@ func (c *Conn) read() (pk any, err error) {
 	defer func() {
 		headerPool.Put(header)
 		if r := recover(); r != nil {
-			err = fmt.Errorf("panic while decoding packet %v: %v", header.PacketID, r)
+			err = fmt.Errorf("%w: panic while decoding packet %v: %v", ErrMalformedPacket, header.PacketID, r)
 		}
 	}()
 	if err := header.Read(buf); err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %w", ErrMalformedPacket, err)
 	}
 
-	factory, ok := c.pool[header.PacketID]
+	factory, ok := pool[header.PacketID]
 	if !ok {
-		return nil, fmt.Errorf("unknown packet ID %v", header.PacketID)
+		return nil, fmt.Errorf("%w: unknown packet ID %v", ErrMalformedPacket, header.PacketID)
 	}
 	pk = factory()
-	pk.(packet.Packet).Marshal(c.protocol.NewReader(buf, c.shieldID, false))
+	pk.(packet.Packet).Marshal(proto.NewReader(buf, shieldID, false))
 	return pk, nil
 }
 
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+
+	"github.com/cooldogedev/spectrum/protocol"
+	"github.com/sandertv/gophertunnel/minecraft"
+	gtprotocol "github.com/sandertv/gophertunnel/minecraft/protocol"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+)
+
+// testPacket is a custom packet sent by a server.
+type testPacket struct {
+	Value string
+}
+
+// ID ...
+func (pk *testPacket) ID() uint32 {
+	return 600
+}
+
+// Marshal ...
+func (pk *testPacket) Marshal(io gtprotocol.IO) {
+	io.String(&pk.Value)
+}
+
+// newTestConn returns a spawned Conn reading the frames written by the server to the other end of the connection.
+func newTestConn(t *testing.T, pool packet.Pool) (*Conn, net.Conn) {
+	t.Helper()
+	conn, serverConn := net.Pipe()
+	c := NewConn(conn, nil, slog.New(slog.NewTextHandler(io.Discard, nil)), false, nil, pool)
+	t.Cleanup(func() {
+		_ = c.Close()
+		_ = serverConn.Close()
+	})
+
+	// The packets written by the Conn are discarded.
+	go func() { _, _ = io.Copy(io.Discard, serverConn) }()
+	if err := c.DoSpawn(); err != nil {
+		t.Fatalf("DoSpawn() error = %v", err)
+	}
+	return c, serverConn
+}
+
+// writeTestFrame writes the packet to the connection as a frame written by a server.
+func writeTestFrame(conn net.Conn, pk packet.Packet) error {
+	payload, err := encodePacket(pk, minecraft.DefaultProtocol, 0)
+	if err != nil {
+		return err
+	}
+	return protocol.NewWriter(conn).Write(append([]byte{packetDecodeNeeded}, payload...))
+}
+
+func TestConnCustomPool(t *testing.T) {
+	tests := []struct {
+		name    string
+		pool    packet.Pool
+		wantErr error
+	}{
+		{name: "registered", pool: packet.Pool{600: func() packet.Packet { return &testPacket{} }}},
+		{name: "unregistered", wantErr: ErrMalformedPacket},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, serverConn := newTestConn(t, tt.pool)
+			go func() { _ = writeTestFrame(serverConn, &testPacket{Value: "custom"}) }()
+
+			pk, err := c.ReadPacket()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ReadPacket() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if custom, ok := pk.(*testPacket); !ok || custom.Value != "custom" {
+				t.Fatalf("ReadPacket() = %#v, want the custom packet", pk)
+			}
+		})
+	}
+}
+
+func TestConnRawTap(t *testing.T) {
+	pk := &testPacket{Value: "tapped"}
+	tests := []struct {
+		name      string
+		direction int
+		transfer  func(c *Conn, serverConn net.Conn) error
+	}{
+		{
+			name:      "read",
+			direction: TapDirectionRead,
+			transfer: func(c *Conn, serverConn net.Conn) error {
+				go func() { _ = writeTestFrame(serverConn, pk) }()
+				_, err := c.ReadPacket()
+				return err
+			},
+		},
+		{
+			name:      "write",
+			direction: TapDirectionWrite,
+			transfer: func(c *Conn, _ net.Conn) error {
+				return c.WritePacket(pk)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, serverConn := newTestConn(t, packet.Pool{600: func() packet.Packet { return &testPacket{} }})
+			var frames [][]byte
+			c.SetRawTap(func(direction int, frame []byte) {
+				if direction == tt.direction {
+					frames = append(frames, frame)
+				}
+			})
+			if err := tt.transfer(c, serverConn); err != nil {
+				t.Fatalf("failed to transfer packet: %v", err)
+			}
+
+			payload, err := encodePacket(pk, minecraft.DefaultProtocol, 0)
+			if err != nil {
+				t.Fatalf("encodePacket() error = %v", err)
+			}
+			if tt.direction == TapDirectionRead {
+				payload = append([]byte{packetDecodeNeeded}, payload...)
+			}
+			if len(frames) != 1 || !bytes.Equal(frames[0], payload) {
+				t.Fatalf("tap received frames %x, want %x", frames, payload)
+			}
+		})
+	}
+}
@@ -1,6 +1,14 @@
 package server
 
-import "github.com/sandertv/gophertunnel/minecraft"
+import (
+	"errors"
+
+	"github.com/sandertv/gophertunnel/minecraft"
+)
+
+// ErrGroupsUnsupported is returned when discovering a server out of a group using a discovery wrapping another
+// discovery that does not implement GroupDiscovery.
+var ErrGroupsUnsupported = errors.New("discovery does not support server groups")
 
 // Discovery defines an interface for discovering servers based on a player's connection.
 type Discovery interface {
@@ -33,3 +41,72 @@ func (s *StaticDiscovery) Discover(_ *minecraft.Conn) (string, error) {
 func (s *StaticDiscovery) DiscoverFallback(_ *minecraft.Conn) (string, error) {
 	return s.fallbackServer, nil
 }
+
+// GroupDiscovery is a Discovery that also discovers servers out of named groups of servers, such as "lobby" or
+// "bedwars", allowing players to be transferred to a kind of server rather than a specific address.
+type GroupDiscovery interface {
+	Discovery
+	// DiscoverGroup determines the best server out of the servers of the group.
+	DiscoverGroup(conn *minecraft.Conn, group string) (string, error)
+}
+
+// DiscoveryResult is a server discovered by a ResultDiscovery, along with the transport it is dialed with.
+type DiscoveryResult struct {
+	// Addr is the address of the server.
+	Addr string
+	// Scheme is the scheme of the transport the server is dialed with, as registered in a transport.Registry.
+	// The server is dialed using the fallback transport of the registry if it is empty.
+	Scheme string
+	// Group is the name of the server group the server was discovered from, such as "lobby", if any. It selects
+	// the animation played when the player is transferred to the server.
+	Group string
+}
+
+// Target returns the address dialed to connect to the server, prefixed by the scheme of its transport if set.
+func (r DiscoveryResult) Target() string {
+	if r.Scheme == "" {
+		return r.Addr
+	}
+	return r.Scheme + "://" + r.Addr
+}
+
+// ResultDiscovery is a Discovery returning a DiscoveryResult rather than a bare address, allowing servers to be
+// dialed using different transports. Sessions prefer its methods over the ones of Discovery.
+type ResultDiscovery interface {
+	Discovery
+	// DiscoverResult determines the primary server.
+	DiscoverResult(conn *minecraft.Conn) (DiscoveryResult, error)
+	// DiscoverFallbackResult determines the fallback server.
+	DiscoverFallbackResult(conn *minecraft.Conn) (DiscoveryResult, error)
+}
+
+// discoverGroup discovers a server out of the group using the discovery, returning ErrGroupsUnsupported if it does
+// not implement GroupDiscovery.
+func discoverGroup(d Discovery, conn *minecraft.Conn, group string) (string, error) {
+	groups, ok := d.(GroupDiscovery)
+	if !ok {
+		return "", ErrGroupsUnsupported
+	}
+	return groups.DiscoverGroup(conn, group)
+}
+
+// discoverResult discovers the primary or fallback server using the discovery, preferring the methods of
+// ResultDiscovery if it is implemented.
+func discoverResult(d Discovery, conn *minecraft.Conn, fallback bool) (DiscoveryResult, error) {
+	if results, ok := d.(ResultDiscovery); ok {
+		if fallback {
+			return results.DiscoverFallbackResult(conn)
+		}
+		return results.DiscoverResult(conn)
+	}
+
+	discover := d.Discover
+	if fallback {
+		discover = d.DiscoverFallback
+	}
+	addr, err := discover(conn)
+	if err != nil {
+		return DiscoveryResult{}, err
+	}
+	return DiscoveryResult{Addr: addr}, nil
+}
@@ -0,0 +1,160 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sandertv/gophertunnel/minecraft"
+	"gopkg.in/yaml.v3"
+)
+
+// FileServers is the list of servers read by a FileDiscovery from its file, encoded in either YAML or JSON.
+type FileServers struct {
+	// Servers are the servers players are initially sent to.
+	Servers []string `yaml:"servers" json:"servers"`
+	// FallbackServers are the servers players are sent to when falling back. Fallback servers are selected out
+	// of Servers if there are none.
+	FallbackServers []string `yaml:"fallback_servers" json:"fallback_servers"`
+	// Groups are named groups of servers players can be transferred to using Session.TransferToGroup.
+	Groups map[string][]string `yaml:"groups" json:"groups"`
+}
+
+// FileDiscovery implements the GroupDiscovery interface with servers read from a YAML or JSON file. The file is
+// checked for changes at an interval, and changes are applied without restarting the proxy. If the file becomes
+// invalid, the servers it last contained are kept until it is fixed.
+type FileDiscovery struct {
+	path     string
+	logger   *slog.Logger
+	strategy Strategy
+	interval time.Duration
+
+	servers FileServers
+	modTime time.Time
+	mu      sync.RWMutex
+
+	cancelFunc context.CancelFunc
+	ctx        context.Context
+}
+
+// NewFileDiscovery creates a new FileDiscovery reading the servers from the file at the provided path, selecting
+// servers using the strategy and checking the file for changes at the given interval until it is closed.
+// An error is returned if the interval is not positive or if the file could not be read initially.
+func NewFileDiscovery(path string, logger *slog.Logger, strategy Strategy, interval time.Duration) (*FileDiscovery, error) {
+	if interval <= 0 {
+		return nil, errors.New("interval must be positive")
+	}
+
+	f := &FileDiscovery{
+		path:     path,
+		logger:   logger,
+		strategy: strategy,
+		interval: interval,
+	}
+	if _, err := f.reload(); err != nil {
+		return nil, err
+	}
+	f.ctx, f.cancelFunc = context.WithCancel(context.Background())
+	go f.watch()
+	return f, nil
+}
+
+// Discover ...
+func (f *FileDiscovery) Discover(conn *minecraft.Conn) (string, error) {
+	return f.selectServer(conn, f.Servers().Servers)
+}
+
+// DiscoverFallback ...
+func (f *FileDiscovery) DiscoverFallback(conn *minecraft.Conn) (string, error) {
+	servers := f.Servers()
+	if len(servers.FallbackServers) == 0 {
+		return f.selectServer(conn, servers.Servers)
+	}
+	return f.selectServer(conn, servers.FallbackServers)
+}
+
+// DiscoverGroup ...
+func (f *FileDiscovery) DiscoverGroup(conn *minecraft.Conn, group string) (string, error) {
+	servers, ok := f.Servers().Groups[group]
+	if !ok {
+		return "", ErrUnknownGroup
+	}
+	return f.selectServer(conn, servers)
+}
+
+// Servers returns the servers currently read from the file.
+func (f *FileDiscovery) Servers() FileServers {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.servers
+}
+
+// Close stops watching the file for changes.
+func (f *FileDiscovery) Close() error {
+	f.cancelFunc()
+	return nil
+}
+
+// selectServer selects one of the servers using the strategy.
+func (f *FileDiscovery) selectServer(conn *minecraft.Conn, servers []string) (string, error) {
+	if len(servers) == 0 {
+		return "", ErrNoServers
+	}
+	return f.strategy.Select(conn, servers)
+}
+
+// watch reloads the file at every interval until the discovery is closed.
+func (f *FileDiscovery) watch() {
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-f.ctx.Done():
+			return
+		case <-ticker.C:
+			if reloaded, err := f.reload(); err != nil {
+				f.logger.Error("failed to reload servers", "path", f.path, "err", err)
+			} else if reloaded {
+				f.logger.Info("reloaded servers", "path", f.path)
+			}
+		}
+	}
+}
+
+// reload reads the file if it was modified since it was last read, reporting whether the servers were replaced.
+func (f *FileDiscovery) reload() (bool, error) {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return false, fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	f.mu.RLock()
+	modTime := f.modTime
+	f.mu.RUnlock()
+	if info.ModTime().Equal(modTime) {
+		return false, nil
+	}
+
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	// YAML is a superset of JSON, so both are decoded by the YAML decoder.
+	var servers FileServers
+	if err := yaml.Unmarshal(data, &servers); err != nil {
+		f.mu.Lock()
+		f.modTime = info.ModTime()
+		f.mu.Unlock()
+		return false, fmt.Errorf("failed to decode file: %w", err)
+	}
+
+	f.mu.Lock()
+	f.servers, f.modTime = servers, info.ModTime()
+	f.mu.Unlock()
+	return true, nil
+}
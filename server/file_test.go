@@ -0,0 +1,142 @@
+package server
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// writeTestFile writes the content to the file at the path, setting its modification time to the provided time
+// so that changes are detected regardless of the resolution of the file system.
+func writeTestFile(t *testing.T, path string, content string, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("Chtimes() error = %v", err)
+	}
+}
+
+// newTestFileDiscovery returns a FileDiscovery reading the servers from a file with the content, which is closed
+// once the test finishes.
+func newTestFileDiscovery(t *testing.T, content string, interval time.Duration) (*FileDiscovery, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "servers.yml")
+	writeTestFile(t, path, content, time.Now().Add(-time.Hour))
+	f, err := NewFileDiscovery(path, slog.New(slog.NewTextHandler(io.Discard, nil)), NewRoundRobin(), interval)
+	if err != nil {
+		t.Fatalf("NewFileDiscovery() error = %v", err)
+	}
+	t.Cleanup(func() { _ = f.Close() })
+	return f, path
+}
+
+func TestNewFileDiscovery(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		missing  bool
+		interval time.Duration
+		wantErr  bool
+	}{
+		{name: "valid", content: "servers: [a]", interval: time.Minute},
+		{name: "missing file", missing: true, interval: time.Minute, wantErr: true},
+		{name: "invalid file", content: "servers: a: b", interval: time.Minute, wantErr: true},
+		{name: "zero interval", content: "servers: [a]", wantErr: true},
+		{name: "negative interval", content: "servers: [a]", interval: -time.Minute, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "servers.yml")
+			if !tt.missing {
+				writeTestFile(t, path, tt.content, time.Now())
+			}
+			f, err := NewFileDiscovery(path, slog.New(slog.NewTextHandler(io.Discard, nil)), NewRoundRobin(), tt.interval)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewFileDiscovery() error = %v, want error %v", err, tt.wantErr)
+			}
+			if f != nil {
+				_ = f.Close()
+			}
+		})
+	}
+}
+
+func TestFileDiscoveryReload(t *testing.T) {
+	initial := FileServers{Servers: []string{"a"}}
+	tests := []struct {
+		name         string
+		content      string
+		unchanged    bool
+		want         FileServers
+		wantReloaded bool
+		wantErr      bool
+	}{
+		{
+			name:         "yaml",
+			content:      "servers: [b]\nfallback_servers: [c]\ngroups:\n  lobby: [d]",
+			want:         FileServers{Servers: []string{"b"}, FallbackServers: []string{"c"}, Groups: map[string][]string{"lobby": {"d"}}},
+			wantReloaded: true,
+		},
+		{name: "json", content: `{"servers": ["b"]}`, want: FileServers{Servers: []string{"b"}}, wantReloaded: true},
+		{name: "unchanged", unchanged: true, want: initial},
+		{name: "invalid", content: "servers: a: b", want: initial, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, path := newTestFileDiscovery(t, "servers: [a]", time.Hour)
+			if !tt.unchanged {
+				writeTestFile(t, path, tt.content, time.Now())
+			}
+
+			reloaded, err := f.reload()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("reload() error = %v, want error %v", err, tt.wantErr)
+			}
+			if reloaded != tt.wantReloaded {
+				t.Fatalf("reload() = %v, want %v", reloaded, tt.wantReloaded)
+			}
+			if got := f.Servers(); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Servers() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileDiscoveryWatch(t *testing.T) {
+	f, path := newTestFileDiscovery(t, "servers: [a]", time.Millisecond*10)
+	writeTestFile(t, path, "servers: [b]", time.Now())
+	deadline := time.Now().Add(time.Second * 10)
+	for {
+		if server, _ := f.Discover(nil); server == "b" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("servers were not reloaded after the file changed")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	// The servers last read are kept while the file is invalid.
+	writeTestFile(t, path, "servers: a: b", time.Now().Add(time.Minute))
+	for {
+		f.mu.RLock()
+		modTime := f.modTime
+		f.mu.RUnlock()
+		if modTime.After(time.Now()) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("invalid file was not read")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	if server, err := f.Discover(nil); server != "b" || err != nil {
+		t.Fatalf("Discover() = %q, %v, want %q", server, err, "b")
+	}
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+
+	"github.com/oschwald/maxminddb-golang"
+	"github.com/sandertv/gophertunnel/minecraft"
+)
+
+// geoRecord holds the fields looked up in GeoIP databases.
+type geoRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	Continent struct {
+		Code string `maxminddb:"code"`
+	} `maxminddb:"continent"`
+}
+
+// GeoDiscovery implements the Discovery interface by wrapping another Discovery, sending players to the server of
+// the region closest to them. The region of a player is located using the IP address of their connection in an
+// MMDB database, such as MaxMind's GeoLite2 Country database. Regions are either country ISO codes, such as "DE", or
+// continent codes, such as "EU", with countries taking precedence over continents. Players whose region could not
+// be located or has no server, as well as players falling back, are discovered using the wrapped discovery, which
+// groups are also discovered with if it implements GroupDiscovery.
+type GeoDiscovery struct {
+	discovery Discovery
+	db        *maxminddb.Reader
+	logger    *slog.Logger
+	regions   map[string]string
+}
+
+// NewGeoDiscovery creates a new GeoDiscovery wrapping the provided discovery, locating players using the MMDB
+// database at the provided path and sending them to the server address mapped to their region.
+func NewGeoDiscovery(discovery Discovery, path string, logger *slog.Logger, regions map[string]string) (*GeoDiscovery, error) {
+	db, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	return &GeoDiscovery{
+		discovery: discovery,
+		db:        db,
+		logger:    logger,
+		regions:   regions,
+	}, nil
+}
+
+// Discover ...
+func (g *GeoDiscovery) Discover(conn *minecraft.Conn) (string, error) {
+	if addr, ok := g.locate(conn); ok {
+		return addr, nil
+	}
+	return g.discovery.Discover(conn)
+}
+
+// DiscoverFallback ...
+func (g *GeoDiscovery) DiscoverFallback(conn *minecraft.Conn) (string, error) {
+	return g.discovery.DiscoverFallback(conn)
+}
+
+// DiscoverGroup ...
+func (g *GeoDiscovery) DiscoverGroup(conn *minecraft.Conn, group string) (string, error) {
+	return discoverGroup(g.discovery, conn, group)
+}
+
+// DiscoverResult ...
+func (g *GeoDiscovery) DiscoverResult(conn *minecraft.Conn) (DiscoveryResult, error) {
+	if addr, ok := g.locate(conn); ok {
+		return DiscoveryResult{Addr: addr}, nil
+	}
+	return discoverResult(g.discovery, conn, false)
+}
+
+// DiscoverFallbackResult ...
+func (g *GeoDiscovery) DiscoverFallbackResult(conn *minecraft.Conn) (DiscoveryResult, error) {
+	return discoverResult(g.discovery, conn, true)
+}
+
+// Close closes the database.
+func (g *GeoDiscovery) Close() error {
+	return g.db.Close()
+}
+
+// locate returns the server address mapped to the region of the player's connection, if any.
+func (g *GeoDiscovery) locate(conn *minecraft.Conn) (string, bool) {
+	host, _, err := net.SplitHostPort(conn.RemoteAddr().String())
+	if err != nil {
+		return "", false
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return "", false
+	}
+
+	var record geoRecord
+	if err := g.db.Lookup(ip, &record); err != nil {
+		g.logger.Debug("failed to locate player", "ip", ip, "err", err)
+		return "", false
+	}
+
+	if addr, ok := g.regions[record.Country.ISOCode]; ok && record.Country.ISOCode != "" {
+		return addr, true
+	}
+
+	if addr, ok := g.regions[record.Continent.Code]; ok && record.Continent.Code != "" {
+		return addr, true
+	}
+	return "", false
+}
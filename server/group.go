@@ -0,0 +1,53 @@
+package server
+
+import (
+	"errors"
+
+	"github.com/sandertv/gophertunnel/minecraft"
+)
+
+// ErrUnknownGroup is returned by group discoveries when discovering a server out of a group that does not exist.
+var ErrUnknownGroup = errors.New("unknown server group")
+
+// GroupedDiscovery implements the GroupDiscovery interface with static groups of server addresses, selecting
+// servers out of a group using a Strategy.
+type GroupedDiscovery struct {
+	groups        map[string][]string
+	group         string
+	fallbackGroup string
+	strategy      Strategy
+}
+
+// NewGroupedDiscovery creates a new GroupedDiscovery with the given groups of server addresses. Players are
+// initially sent to a server of the group, and to a server of the fallback group when falling back.
+func NewGroupedDiscovery(groups map[string][]string, group string, fallbackGroup string, strategy Strategy) *GroupedDiscovery {
+	return &GroupedDiscovery{
+		groups:        groups,
+		group:         group,
+		fallbackGroup: fallbackGroup,
+		strategy:      strategy,
+	}
+}
+
+// Discover ...
+func (g *GroupedDiscovery) Discover(conn *minecraft.Conn) (string, error) {
+	return g.DiscoverGroup(conn, g.group)
+}
+
+// DiscoverFallback ...
+func (g *GroupedDiscovery) DiscoverFallback(conn *minecraft.Conn) (string, error) {
+	return g.DiscoverGroup(conn, g.fallbackGroup)
+}
+
+// DiscoverGroup ...
+func (g *GroupedDiscovery) DiscoverGroup(conn *minecraft.Conn, group string) (string, error) {
+	servers, ok := g.groups[group]
+	if !ok {
+		return "", ErrUnknownGroup
+	}
+
+	if len(servers) == 0 {
+		return "", ErrNoServers
+	}
+	return g.strategy.Select(conn, servers)
+}
@@ -0,0 +1,227 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/cooldogedev/spectrum/transport"
+	"github.com/sandertv/gophertunnel/minecraft"
+)
+
+// defaultHealthCheckInterval is the interval at which servers are checked by a HealthChecked discovery created with
+// an interval of 0 or below.
+const defaultHealthCheckInterval = time.Second * 5
+
+// HealthChecked implements the Discovery interface by wrapping another Discovery, periodically checking a list of
+// servers and refusing to return the ones that failed their last check. When the wrapped discovery returns an
+// unhealthy server for Discover, the result of its DiscoverFallback is used instead. Servers that are not checked,
+// or that have not been checked yet, are considered healthy. The servers discovered out of groups or as results are
+// checked as well when the wrapped discovery implements GroupDiscovery or ResultDiscovery.
+type HealthChecked struct {
+	discovery   Discovery
+	transport   transport.Transport
+	logger      *slog.Logger
+	servers     []string
+	interval    time.Duration
+	queryStatus bool
+
+	health map[string]serverHealth
+	mu     sync.RWMutex
+
+	cancelFunc context.CancelFunc
+	ctx        context.Context
+}
+
+// serverHealth is the result of the last health check of a server.
+type serverHealth struct {
+	healthy bool
+	latency time.Duration
+}
+
+// NewHealthChecked creates a new HealthChecked discovery wrapping the provided discovery, checking that the provided
+// servers can be dialed over the transport at the given interval until it is closed. Servers are checked every 5
+// seconds if the interval is 0 or below.
+func NewHealthChecked(discovery Discovery, t transport.Transport, logger *slog.Logger, servers []string, interval time.Duration) *HealthChecked {
+	return newHealthChecked(discovery, t, logger, servers, interval, false)
+}
+
+// NewStatusHealthChecked creates a new HealthChecked discovery wrapping the provided discovery, querying the status
+// of the provided servers over the transport at the given interval until it is closed. Unlike NewHealthChecked,
+// servers that are reachable but unresponsive are detected as well, however every server checked must support
+// status queries, as the ones that do not are considered unhealthy.
+func NewStatusHealthChecked(discovery Discovery, t transport.Transport, logger *slog.Logger, servers []string, interval time.Duration) *HealthChecked {
+	return newHealthChecked(discovery, t, logger, servers, interval, true)
+}
+
+func newHealthChecked(discovery Discovery, t transport.Transport, logger *slog.Logger, servers []string, interval time.Duration, queryStatus bool) *HealthChecked {
+	if interval <= 0 {
+		interval = defaultHealthCheckInterval
+	}
+	h := &HealthChecked{
+		discovery:   discovery,
+		transport:   t,
+		logger:      logger,
+		servers:     servers,
+		interval:    interval,
+		queryStatus: queryStatus,
+
+		health: make(map[string]serverHealth),
+	}
+	h.ctx, h.cancelFunc = context.WithCancel(context.Background())
+	go h.run()
+	return h
+}
+
+// Discover ...
+func (h *HealthChecked) Discover(conn *minecraft.Conn) (string, error) {
+	addr, err := h.discovery.Discover(conn)
+	if err != nil {
+		return "", err
+	}
+
+	if h.Healthy(addr) {
+		return addr, nil
+	}
+	h.logger.Debug("discovered unhealthy server, discovering fallback", "addr", addr)
+	return h.DiscoverFallback(conn)
+}
+
+// DiscoverFallback ...
+func (h *HealthChecked) DiscoverFallback(conn *minecraft.Conn) (string, error) {
+	addr, err := h.discovery.DiscoverFallback(conn)
+	if err != nil {
+		return "", err
+	}
+
+	if !h.Healthy(addr) {
+		return "", ErrNoServers
+	}
+	return addr, nil
+}
+
+// DiscoverGroup discovers a server out of the group using the wrapped discovery, which must implement
+// GroupDiscovery. ErrNoServers is returned if the server discovered is unhealthy, as falling back would move the
+// player out of the group.
+func (h *HealthChecked) DiscoverGroup(conn *minecraft.Conn, group string) (string, error) {
+	addr, err := discoverGroup(h.discovery, conn, group)
+	if err != nil {
+		return "", err
+	}
+
+	if !h.Healthy(addr) {
+		return "", ErrNoServers
+	}
+	return addr, nil
+}
+
+// DiscoverResult ...
+func (h *HealthChecked) DiscoverResult(conn *minecraft.Conn) (DiscoveryResult, error) {
+	result, err := discoverResult(h.discovery, conn, false)
+	if err != nil {
+		return DiscoveryResult{}, err
+	}
+
+	if h.Healthy(result.Addr) {
+		return result, nil
+	}
+	h.logger.Debug("discovered unhealthy server, discovering fallback", "addr", result.Addr)
+	return h.DiscoverFallbackResult(conn)
+}
+
+// DiscoverFallbackResult ...
+func (h *HealthChecked) DiscoverFallbackResult(conn *minecraft.Conn) (DiscoveryResult, error) {
+	result, err := discoverResult(h.discovery, conn, true)
+	if err != nil {
+		return DiscoveryResult{}, err
+	}
+
+	if !h.Healthy(result.Addr) {
+		return DiscoveryResult{}, ErrNoServers
+	}
+	return result, nil
+}
+
+// Healthy reports whether the server passed its last health check.
+func (h *HealthChecked) Healthy(addr string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	health, ok := h.health[addr]
+	return !ok || health.healthy
+}
+
+// Latency returns the latency measured by the last health check of the server, reporting false if the server
+// has not been checked successfully.
+func (h *HealthChecked) Latency(addr string) (time.Duration, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	health, ok := h.health[addr]
+	return health.latency, ok && health.healthy
+}
+
+// Close stops the health checks.
+func (h *HealthChecked) Close() error {
+	h.cancelFunc()
+	return nil
+}
+
+// run checks the health of the servers at every interval until the discovery is closed.
+func (h *HealthChecked) run() {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+	for {
+		h.check()
+		select {
+		case <-h.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check checks all the servers concurrently, recording whether they passed the check.
+func (h *HealthChecked) check() {
+	var wg sync.WaitGroup
+	for _, addr := range h.servers {
+		wg.Add(1)
+		go func(addr string) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(h.ctx, h.interval)
+			defer cancel()
+			latency, err := h.probe(ctx, addr)
+			if err != nil && h.ctx.Err() != nil {
+				return
+			}
+
+			h.mu.Lock()
+			previous, checked := h.health[addr]
+			h.health[addr] = serverHealth{healthy: err == nil, latency: latency}
+			h.mu.Unlock()
+			if err != nil && (!checked || previous.healthy) {
+				h.logger.Warn("server failed health check", "addr", addr, "err", err)
+			} else if err == nil && checked && !previous.healthy {
+				h.logger.Info("server recovered", "addr", addr)
+			}
+		}(addr)
+	}
+	wg.Wait()
+}
+
+// probe checks the server at the address, returning the latency measured. The status of the server is queried if
+// the discovery was created using NewStatusHealthChecked, and the server is otherwise only dialed.
+func (h *HealthChecked) probe(ctx context.Context, addr string) (time.Duration, error) {
+	if h.queryStatus {
+		status, err := QueryStatus(ctx, h.transport, addr)
+		return status.Latency, err
+	}
+
+	start := time.Now()
+	conn, err := h.transport.Dial(ctx, addr)
+	if err != nil {
+		return 0, err
+	}
+	latency := time.Since(start)
+	_ = conn.Close()
+	return latency, nil
+}
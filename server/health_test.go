@@ -0,0 +1,124 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/sandertv/gophertunnel/minecraft"
+)
+
+// testResultDiscovery is a ResultDiscovery returning fixed results.
+type testResultDiscovery struct {
+	result, fallback DiscoveryResult
+}
+
+// Discover ...
+func (d testResultDiscovery) Discover(*minecraft.Conn) (string, error) {
+	return d.result.Addr, nil
+}
+
+// DiscoverFallback ...
+func (d testResultDiscovery) DiscoverFallback(*minecraft.Conn) (string, error) {
+	return d.fallback.Addr, nil
+}
+
+// DiscoverResult ...
+func (d testResultDiscovery) DiscoverResult(*minecraft.Conn) (DiscoveryResult, error) {
+	return d.result, nil
+}
+
+// DiscoverFallbackResult ...
+func (d testResultDiscovery) DiscoverFallbackResult(*minecraft.Conn) (DiscoveryResult, error) {
+	return d.fallback, nil
+}
+
+// newTestHealthChecked returns a HealthChecked discovery wrapping the discovery, treating the unhealthy servers
+// as if they failed their last check. It is closed once the test finishes.
+func newTestHealthChecked(t *testing.T, discovery Discovery, unhealthy ...string) *HealthChecked {
+	t.Helper()
+	h := NewHealthChecked(discovery, nil, slog.New(slog.NewTextHandler(io.Discard, nil)), nil, time.Minute)
+	t.Cleanup(func() { _ = h.Close() })
+	h.mu.Lock()
+	for _, addr := range unhealthy {
+		h.health[addr] = serverHealth{}
+	}
+	h.mu.Unlock()
+	return h
+}
+
+func TestNewHealthCheckedInterval(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+		want     time.Duration
+	}{
+		{name: "interval", interval: time.Second, want: time.Second},
+		{name: "zero", want: defaultHealthCheckInterval},
+		{name: "negative", interval: -time.Second, want: defaultHealthCheckInterval},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHealthChecked(NewStaticDiscovery("a", "b"), nil, slog.New(slog.NewTextHandler(io.Discard, nil)), nil, tt.interval)
+			defer h.Close()
+			if h.interval != tt.want {
+				t.Fatalf("interval = %v, want %v", h.interval, tt.want)
+			}
+		})
+	}
+}
+
+func TestHealthCheckedDiscoverGroup(t *testing.T) {
+	grouped := NewGroupedDiscovery(map[string][]string{"lobby": {"a"}}, "lobby", "lobby", NewRoundRobin())
+	tests := []struct {
+		name      string
+		discovery Discovery
+		unhealthy []string
+		group     string
+		want      string
+		wantErr   error
+	}{
+		{name: "healthy", discovery: grouped, group: "lobby", want: "a"},
+		{name: "unhealthy", discovery: grouped, unhealthy: []string{"a"}, group: "lobby", wantErr: ErrNoServers},
+		{name: "unknown group", discovery: grouped, group: "bedwars", wantErr: ErrUnknownGroup},
+		{name: "groups unsupported", discovery: NewStaticDiscovery("a", "b"), group: "lobby", wantErr: ErrGroupsUnsupported},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newTestHealthChecked(t, tt.discovery, tt.unhealthy...).DiscoverGroup(nil, tt.group)
+			if got != tt.want || !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DiscoverGroup() = %q, %v, want %q, %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHealthCheckedDiscoverResult(t *testing.T) {
+	results := testResultDiscovery{
+		result:   DiscoveryResult{Addr: "a", Scheme: "tcp", Group: "lobby"},
+		fallback: DiscoveryResult{Addr: "b", Scheme: "tcp"},
+	}
+	tests := []struct {
+		name      string
+		discovery Discovery
+		unhealthy []string
+		want      DiscoveryResult
+		wantErr   error
+	}{
+		{name: "healthy", discovery: results, want: results.result},
+		{name: "unhealthy", discovery: results, unhealthy: []string{"a"}, want: results.fallback},
+		{name: "no healthy servers", discovery: results, unhealthy: []string{"a", "b"}, wantErr: ErrNoServers},
+		{name: "addresses", discovery: NewStaticDiscovery("a", "b"), want: DiscoveryResult{Addr: "a"}},
+		{name: "unhealthy address", discovery: NewStaticDiscovery("a", "b"), unhealthy: []string{"a"}, want: DiscoveryResult{Addr: "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newTestHealthChecked(t, tt.discovery, tt.unhealthy...).DiscoverResult(nil)
+			if got != tt.want || !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DiscoverResult() = %+v, %v, want %+v, %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
@@ -7,4 +7,7 @@ const (
 	IDLatency
 	IDTransfer
 	IDUpdateCache
+	IDStatusRequest
+	IDStatusResponse
+	IDTransferGroup
 )
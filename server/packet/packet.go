@@ -5,10 +5,13 @@ import "github.com/sandertv/gophertunnel/minecraft/protocol/packet"
 func init() {
 	packet.RegisterPacketFromClient(IDConnectionRequest, func() packet.Packet { return &ConnectionRequest{} })
 	packet.RegisterPacketFromClient(IDLatency, func() packet.Packet { return &Latency{} })
+	packet.RegisterPacketFromClient(IDStatusRequest, func() packet.Packet { return &StatusRequest{} })
 
 	packet.RegisterPacketFromServer(IDConnectionResponse, func() packet.Packet { return &ConnectionResponse{} })
 	packet.RegisterPacketFromServer(IDFlush, func() packet.Packet { return &Flush{} })
 	packet.RegisterPacketFromServer(IDLatency, func() packet.Packet { return &Latency{} })
 	packet.RegisterPacketFromServer(IDTransfer, func() packet.Packet { return &Transfer{} })
 	packet.RegisterPacketFromServer(IDUpdateCache, func() packet.Packet { return &UpdateCache{} })
+	packet.RegisterPacketFromServer(IDStatusResponse, func() packet.Packet { return &StatusResponse{} })
+	packet.RegisterPacketFromServer(IDTransferGroup, func() packet.Packet { return &TransferGroup{} })
 }
@@ -0,0 +1,20 @@
+package packet
+
+import "github.com/sandertv/gophertunnel/minecraft/protocol"
+
+// StatusRequest is sent by the proxy to query the status of a server without establishing a session.
+// The server responds to this packet with a StatusResponse packet and closes the connection.
+type StatusRequest struct {
+	// Timestamp is the timestamp (in milliseconds) when the request was sent.
+	Timestamp int64
+}
+
+// ID ...
+func (pk *StatusRequest) ID() uint32 {
+	return IDStatusRequest
+}
+
+// Marshal ...
+func (pk *StatusRequest) Marshal(io protocol.IO) {
+	io.Int64(&pk.Timestamp)
+}
@@ -0,0 +1,31 @@
+package packet
+
+import "github.com/sandertv/gophertunnel/minecraft/protocol"
+
+// StatusResponse is sent by the server in response to a StatusRequest.
+type StatusResponse struct {
+	// MOTD is the message of the day of the server.
+	MOTD string
+	// Version is the version of the game the server is running.
+	Version string
+	// PlayerCount is the amount of players currently connected to the server.
+	PlayerCount int32
+	// MaxPlayers is the maximum amount of players that can be connected to the server.
+	MaxPlayers int32
+	// Timestamp is the timestamp of the StatusRequest the server is responding to.
+	Timestamp int64
+}
+
+// ID ...
+func (pk *StatusResponse) ID() uint32 {
+	return IDStatusResponse
+}
+
+// Marshal ...
+func (pk *StatusResponse) Marshal(io protocol.IO) {
+	io.String(&pk.MOTD)
+	io.String(&pk.Version)
+	io.Varint32(&pk.PlayerCount)
+	io.Varint32(&pk.MaxPlayers)
+	io.Int64(&pk.Timestamp)
+}
@@ -0,0 +1,20 @@
+package packet
+
+import "github.com/sandertv/gophertunnel/minecraft/protocol"
+
+// TransferGroup is sent by the server to initiate a server transfer to the best server of a group, as
+// determined by the proxy's discovery.
+type TransferGroup struct {
+	// Group is the name of the group of the new server.
+	Group string
+}
+
+// ID ...
+func (pk *TransferGroup) ID() uint32 {
+	return IDTransferGroup
+}
+
+// Marshal ...
+func (pk *TransferGroup) Marshal(io protocol.IO) {
+	io.String(&pk.Group)
+}
@@ -0,0 +1,157 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sandertv/gophertunnel/minecraft"
+)
+
+// redisTimeout is the timeout of the Redis commands issued by RedisDiscovery.
+const redisTimeout = time.Second * 5
+
+// removeExpiredScript removes the entries passed as pairs of addresses and entries from the hash, skipping the ones
+// that changed since they were read so that servers refreshing their entry concurrently are not removed.
+var removeExpiredScript = redis.NewScript(`
+local removed = 0
+for i = 1, #ARGV, 2 do
+	if redis.call("HGET", KEYS[1], ARGV[i]) == ARGV[i + 1] then
+		removed = removed + redis.call("HDEL", KEYS[1], ARGV[i])
+	end
+end
+return removed
+`)
+
+// RedisServer is the entry a server registers in Redis to be discovered by a RedisDiscovery. Entries are stored as
+// JSON in a Redis hash, keyed by the address of the server, and must be refreshed by the server before they expire.
+type RedisServer struct {
+	// Addr is the address the proxy dials to connect to the server.
+	Addr string `json:"addr"`
+	// Group is the group of the server, such as "lobby", used to discover servers of a specific kind.
+	Group string `json:"group"`
+	// PlayerCount is the amount of players currently connected to the server.
+	PlayerCount int `json:"player_count"`
+	// Heartbeat is the time of the last update of the entry in milliseconds since the Unix epoch.
+	Heartbeat int64 `json:"heartbeat"`
+}
+
+// RegisterRedisServer registers or refreshes the entry of a server in the hash stored at the provided key,
+// setting its heartbeat to the current time. It is intended for servers written in Go.
+func RegisterRedisServer(ctx context.Context, client redis.UniversalClient, key string, server RedisServer) error {
+	server.Heartbeat = time.Now().UnixMilli()
+	entry, err := json.Marshal(server)
+	if err != nil {
+		return err
+	}
+	return client.HSet(ctx, key, server.Addr, entry).Err()
+}
+
+// RedisDiscovery implements the GroupDiscovery interface by discovering the servers registered in a Redis hash. Servers are
+// selected out of the ones of the configured groups whose entries have not expired, using the server with the least
+// players unless a Strategy is provided.
+type RedisDiscovery struct {
+	client        redis.UniversalClient
+	key           string
+	logger        *slog.Logger
+	group         string
+	fallbackGroup string
+	expiry        time.Duration
+	strategy      Strategy
+}
+
+// NewRedisDiscovery creates a new RedisDiscovery discovering the servers registered in the hash stored at the provided
+// key. Entries with a heartbeat older than the expiry are ignored and removed from the hash, and the strategy may be
+// nil.
+func NewRedisDiscovery(client redis.UniversalClient, key string, logger *slog.Logger, group string, fallbackGroup string, expiry time.Duration, strategy Strategy) *RedisDiscovery {
+	return &RedisDiscovery{
+		client:        client,
+		key:           key,
+		logger:        logger,
+		group:         group,
+		fallbackGroup: fallbackGroup,
+		expiry:        expiry,
+		strategy:      strategy,
+	}
+}
+
+// Discover ...
+func (r *RedisDiscovery) Discover(conn *minecraft.Conn) (string, error) {
+	return r.discover(conn, r.group)
+}
+
+// DiscoverFallback ...
+func (r *RedisDiscovery) DiscoverFallback(conn *minecraft.Conn) (string, error) {
+	return r.discover(conn, r.fallbackGroup)
+}
+
+// DiscoverGroup ...
+func (r *RedisDiscovery) DiscoverGroup(conn *minecraft.Conn, group string) (string, error) {
+	return r.discover(conn, group)
+}
+
+// Servers returns the servers of the group whose entries have not expired. Entries that could not be decoded are
+// skipped, and expired entries of any group are removed from the hash.
+func (r *RedisDiscovery) Servers(ctx context.Context, group string) ([]RedisServer, error) {
+	entries, err := r.client.HGetAll(ctx, r.key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch servers: %w", err)
+	}
+
+	now := time.Now()
+	servers := make([]RedisServer, 0, len(entries))
+	var expired []any
+	for addr, entry := range entries {
+		var server RedisServer
+		if err := json.Unmarshal([]byte(entry), &server); err != nil {
+			r.logger.Warn("skipped malformed server entry", "addr", addr, "err", err)
+			continue
+		}
+
+		if now.Sub(time.UnixMilli(server.Heartbeat)) > r.expiry {
+			expired = append(expired, addr, entry)
+		} else if server.Group == group {
+			servers = append(servers, server)
+		}
+	}
+
+	if len(expired) > 0 {
+		if err := removeExpiredScript.Run(ctx, r.client, []string{r.key}, expired...).Err(); err != nil {
+			r.logger.Debug("failed to remove expired server entries", "err", err)
+		}
+	}
+	return servers, nil
+}
+
+// discover selects a server out of the servers of the group.
+func (r *RedisDiscovery) discover(conn *minecraft.Conn, group string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	servers, err := r.Servers(ctx, group)
+	if err != nil {
+		return "", err
+	}
+
+	if len(servers) == 0 {
+		return "", ErrNoServers
+	}
+
+	if r.strategy != nil {
+		addrs := make([]string, 0, len(servers))
+		for _, server := range servers {
+			addrs = append(addrs, server.Addr)
+		}
+		return r.strategy.Select(conn, addrs)
+	}
+
+	selected := servers[0]
+	for _, server := range servers[1:] {
+		if server.PlayerCount < selected.PlayerCount {
+			selected = server
+		}
+	}
+	return selected.Addr, nil
+}
@@ -0,0 +1,47 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/cooldogedev/spectrum/transport"
+	"github.com/sandertv/gophertunnel/minecraft"
+)
+
+// srvLookupTimeout is the timeout of the SRV lookups performed by SRVDiscovery.
+const srvLookupTimeout = time.Second * 5
+
+// SRVDiscovery implements the Discovery interface by resolving the _spectrum._tcp SRV records of a domain, allowing
+// servers to be managed in DNS. Records are selected according to their priority and weight, and are cached by the
+// underlying transport.Resolver for its TTL.
+type SRVDiscovery struct {
+	resolver       *transport.Resolver
+	domain         string
+	fallbackDomain string
+}
+
+// NewSRVDiscovery creates a new SRVDiscovery resolving the SRV records of the provided domains using the resolver.
+func NewSRVDiscovery(resolver *transport.Resolver, domain string, fallbackDomain string) *SRVDiscovery {
+	return &SRVDiscovery{
+		resolver:       resolver,
+		domain:         domain,
+		fallbackDomain: fallbackDomain,
+	}
+}
+
+// Discover ...
+func (s *SRVDiscovery) Discover(_ *minecraft.Conn) (string, error) {
+	return s.resolve(s.domain)
+}
+
+// DiscoverFallback ...
+func (s *SRVDiscovery) DiscoverFallback(_ *minecraft.Conn) (string, error) {
+	return s.resolve(s.fallbackDomain)
+}
+
+// resolve selects a server out of the SRV records of the domain.
+func (s *SRVDiscovery) resolve(domain string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), srvLookupTimeout)
+	defer cancel()
+	return s.resolver.Resolve(ctx, domain)
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cooldogedev/spectrum/transport"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// newTestResolver returns a Resolver answering SRV lookups with the records of the host, using an in-memory DNS
+// server rather than the system's.
+func newTestResolver(records map[string][]*net.SRV) *transport.Resolver {
+	return transport.NewDNSResolver(&net.Resolver{
+		PreferGo: true,
+		Dial: func(context.Context, string, string) (net.Conn, error) {
+			conn, serverConn := net.Pipe()
+			go serveDNS(serverConn, records)
+			return conn, nil
+		},
+	}, time.Minute)
+}
+
+// serveDNS answers the DNS queries read from the connection with the SRV records of the queried host, framed as
+// over TCP.
+func serveDNS(conn net.Conn, records map[string][]*net.SRV) {
+	defer conn.Close()
+	for {
+		var length uint16
+		if err := binary.Read(conn, binary.BigEndian, &length); err != nil {
+			return
+		}
+		query := make([]byte, length)
+		if _, err := io.ReadFull(conn, query); err != nil {
+			return
+		}
+
+		var parser dnsmessage.Parser
+		header, err := parser.Start(query)
+		if err != nil {
+			return
+		}
+		question, err := parser.Question()
+		if err != nil {
+			return
+		}
+
+		host := strings.TrimSuffix(strings.TrimPrefix(question.Name.String(), "_spectrum._tcp."), ".")
+		answers, ok := records[host]
+		rcode := dnsmessage.RCodeSuccess
+		if !ok || question.Type != dnsmessage.TypeSRV {
+			rcode = dnsmessage.RCodeNameError
+		}
+		builder := dnsmessage.NewBuilder(make([]byte, 2, 512), dnsmessage.Header{ID: header.ID, Response: true, Authoritative: true, RCode: rcode})
+		_ = builder.StartQuestions()
+		_ = builder.Question(question)
+		_ = builder.StartAnswers()
+		for _, record := range answers {
+			_ = builder.SRVResource(
+				dnsmessage.ResourceHeader{Name: question.Name, Class: dnsmessage.ClassINET, TTL: 60},
+				dnsmessage.SRVResource{Priority: record.Priority, Weight: record.Weight, Port: record.Port, Target: dnsmessage.MustNewName(record.Target)},
+			)
+		}
+		response, err := builder.Finish()
+		if err != nil {
+			return
+		}
+		binary.BigEndian.PutUint16(response, uint16(len(response)-2))
+		if _, err := conn.Write(response); err != nil {
+			return
+		}
+	}
+}
+
+func TestSRVDiscovery(t *testing.T) {
+	records := map[string][]*net.SRV{
+		"play.example.com":     {{Target: "backend.example.com.", Port: 19133}},
+		"fallback.example.com": {{Target: "fallback.example.com.", Port: 19134}},
+		"priority.example.com": {{Target: "secondary.example.com.", Port: 19133, Priority: 20}, {Target: "primary.example.com.", Port: 19133, Priority: 10}},
+	}
+	tests := []struct {
+		name           string
+		domain         string
+		fallbackDomain string
+		want           string
+		wantFallback   string
+		wantErr        bool
+	}{
+		{name: "srv", domain: "play.example.com", fallbackDomain: "fallback.example.com", want: "backend.example.com:19133", wantFallback: "fallback.example.com:19134"},
+		{name: "lowest priority", domain: "priority.example.com", fallbackDomain: "priority.example.com", want: "primary.example.com:19133", wantFallback: "primary.example.com:19133"},
+		{name: "addresses", domain: "127.0.0.1:19132", fallbackDomain: "[::1]:19133", want: "127.0.0.1:19132", wantFallback: "[::1]:19133"},
+		{name: "no records", domain: "missing.example.com", fallbackDomain: "missing.example.com", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewSRVDiscovery(newTestResolver(records), tt.domain, tt.fallbackDomain)
+			got, err := d.Discover(nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Discover() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("Discover() = %q, want %q", got, tt.want)
+			}
+
+			fallback, err := d.DiscoverFallback(nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DiscoverFallback() error = %v, want error %v", err, tt.wantErr)
+			}
+			if fallback != tt.wantFallback {
+				t.Fatalf("DiscoverFallback() = %q, want %q", fallback, tt.wantFallback)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cooldogedev/spectrum/protocol"
+	spectrumpacket "github.com/cooldogedev/spectrum/server/packet"
+	"github.com/cooldogedev/spectrum/transport"
+	"github.com/sandertv/gophertunnel/minecraft"
+)
+
+// Status represents the status of a server as reported by QueryStatus.
+type Status struct {
+	// MOTD is the message of the day of the server.
+	MOTD string
+	// Version is the version of the game the server is running.
+	Version string
+	// PlayerCount is the amount of players currently connected to the server.
+	PlayerCount int
+	// MaxPlayers is the maximum amount of players that can be connected to the server.
+	MaxPlayers int
+	// Latency is the round-trip time of the query, including dialing the server.
+	Latency time.Duration
+}
+
+// QueryStatus queries the status of the server at the specified address over the provided transport, without
+// establishing a session. The provided context is used for managing timeouts and cancellations, and should
+// always carry a deadline as servers that do not support status queries might never respond.
+func QueryStatus(ctx context.Context, t transport.Transport, addr string) (Status, error) {
+	start := time.Now()
+	conn, err := t.Dial(ctx, addr)
+	if err != nil {
+		return Status{}, err
+	}
+	defer conn.Close()
+
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.Close()
+	})
+	defer stop()
+
+	payload, err := encodePacket(&spectrumpacket.StatusRequest{Timestamp: start.UnixMilli()}, minecraft.DefaultProtocol, 0)
+	if err != nil {
+		return Status{}, err
+	}
+
+	if err := protocol.NewWriter(conn).Write(payload); err != nil {
+		return Status{}, fmt.Errorf("failed to write status request: %w", err)
+	}
+
+	payload, err = protocol.NewReader(conn).ReadPacket()
+	if err != nil {
+		if ctxErr := context.Cause(ctx); ctxErr != nil {
+			return Status{}, ctxErr
+		}
+		return Status{}, fmt.Errorf("failed to read status response: %w", err)
+	}
+
+	pk, err := decodePacket(payload, minecraft.DefaultProtocol.Packets(false), minecraft.DefaultProtocol, 0)
+	if err != nil {
+		return Status{}, err
+	}
+
+	response, ok := pk.(*spectrumpacket.StatusResponse)
+	if !ok {
+		return Status{}, errors.New("expected status response")
+	}
+	return Status{
+		MOTD:        response.MOTD,
+		Version:     response.Version,
+		PlayerCount: int(response.PlayerCount),
+		MaxPlayers:  int(response.MaxPlayers),
+		Latency:     time.Since(start),
+	}, nil
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/cooldogedev/spectrum/protocol"
+	spectrumpacket "github.com/cooldogedev/spectrum/server/packet"
+	"github.com/sandertv/gophertunnel/minecraft"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+)
+
+// testTransport is a transport connecting to an in-memory server served by its function. Dials fail if it has
+// no function.
+type testTransport func(conn net.Conn)
+
+// Dial ...
+func (t testTransport) Dial(context.Context, string) (io.ReadWriteCloser, error) {
+	if t == nil {
+		return nil, errors.New("connection refused")
+	}
+
+	conn, serverConn := net.Pipe()
+	go t(serverConn)
+	return conn, nil
+}
+
+// respondStatus returns a function responding to a status request with the packet, or never responding if it
+// is nil.
+func respondStatus(pk packet.Packet) func(conn net.Conn) {
+	return func(conn net.Conn) {
+		defer conn.Close()
+		payload, err := protocol.NewReader(conn).ReadPacket()
+		if err != nil {
+			return
+		}
+
+		request, err := decodePacket(append([]byte{packetDecodeNeeded}, payload...), minecraft.DefaultProtocol.Packets(true), minecraft.DefaultProtocol, 0)
+		if _, ok := request.(*spectrumpacket.StatusRequest); !ok || err != nil {
+			return
+		}
+
+		if pk == nil {
+			_, _ = io.Copy(io.Discard, conn)
+			return
+		}
+		payload, _ = encodePacket(pk, minecraft.DefaultProtocol, 0)
+		_ = protocol.NewWriter(conn).Write(append([]byte{packetDecodeNeeded}, payload...))
+	}
+}
+
+func TestQueryStatus(t *testing.T) {
+	tests := []struct {
+		name      string
+		transport testTransport
+		want      Status
+		wantErr   error
+	}{
+		{
+			name: "responded",
+			transport: respondStatus(&spectrumpacket.StatusResponse{
+				MOTD:        "Spectrum",
+				Version:     "1.21.0",
+				PlayerCount: 10,
+				MaxPlayers:  100,
+			}),
+			want: Status{MOTD: "Spectrum", Version: "1.21.0", PlayerCount: 10, MaxPlayers: 100},
+		},
+		{name: "unexpected packet", transport: respondStatus(&spectrumpacket.Flush{}), wantErr: errors.New("expected status response")},
+		{name: "no response", transport: respondStatus(nil), wantErr: context.DeadlineExceeded},
+		{name: "dial failed", wantErr: errors.New("connection refused")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*200)
+			defer cancel()
+			status, err := QueryStatus(ctx, tt.transport, "server")
+			if tt.wantErr != nil {
+				if err == nil || (!errors.Is(err, tt.wantErr) && err.Error() != tt.wantErr.Error()) {
+					t.Fatalf("QueryStatus() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("QueryStatus() error = %v", err)
+			}
+
+			if status.Latency <= 0 {
+				t.Fatalf("QueryStatus() latency = %v, want a positive latency", status.Latency)
+			}
+			status.Latency = 0
+			if status != tt.want {
+				t.Fatalf("QueryStatus() = %+v, want %+v", status, tt.want)
+			}
+		})
+	}
+}
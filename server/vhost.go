@@ -0,0 +1,74 @@
+package server
+
+import (
+	"net"
+	"strings"
+
+	"github.com/sandertv/gophertunnel/minecraft"
+)
+
+// VirtualHost returns the hostname the player connected to the proxy with, as sent by the client in its login
+// request, without its port and in lower case. It allows a single proxy to route players depending on the
+// address they joined with.
+func VirtualHost(conn *minecraft.Conn) string {
+	addr := conn.ClientData().ServerAddress
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	return strings.ToLower(strings.TrimSuffix(addr, "."))
+}
+
+// VirtualHostDiscovery implements the Discovery interface by delegating discovery to the Discovery mapped to the
+// virtual host the player connected with, such as "lobby.example.com". Players connecting with a host that is not
+// mapped are discovered using the default discovery. Groups and results are discovered using the mapped discovery
+// when it implements GroupDiscovery or ResultDiscovery.
+type VirtualHostDiscovery struct {
+	hosts     map[string]Discovery
+	discovery Discovery
+}
+
+// NewVirtualHostDiscovery creates a new VirtualHostDiscovery with the provided discoveries mapped by host, and the
+// default discovery used for any other host. Hosts are matched case-insensitively.
+func NewVirtualHostDiscovery(hosts map[string]Discovery, discovery Discovery) *VirtualHostDiscovery {
+	normalised := make(map[string]Discovery, len(hosts))
+	for host, d := range hosts {
+		normalised[strings.ToLower(host)] = d
+	}
+	return &VirtualHostDiscovery{
+		hosts:     normalised,
+		discovery: discovery,
+	}
+}
+
+// Discover ...
+func (v *VirtualHostDiscovery) Discover(conn *minecraft.Conn) (string, error) {
+	return v.lookup(conn).Discover(conn)
+}
+
+// DiscoverFallback ...
+func (v *VirtualHostDiscovery) DiscoverFallback(conn *minecraft.Conn) (string, error) {
+	return v.lookup(conn).DiscoverFallback(conn)
+}
+
+// DiscoverGroup ...
+func (v *VirtualHostDiscovery) DiscoverGroup(conn *minecraft.Conn, group string) (string, error) {
+	return discoverGroup(v.lookup(conn), conn, group)
+}
+
+// DiscoverResult ...
+func (v *VirtualHostDiscovery) DiscoverResult(conn *minecraft.Conn) (DiscoveryResult, error) {
+	return discoverResult(v.lookup(conn), conn, false)
+}
+
+// DiscoverFallbackResult ...
+func (v *VirtualHostDiscovery) DiscoverFallbackResult(conn *minecraft.Conn) (DiscoveryResult, error) {
+	return discoverResult(v.lookup(conn), conn, true)
+}
+
+// lookup returns the discovery mapped to the virtual host of the player's connection.
+func (v *VirtualHostDiscovery) lookup(conn *minecraft.Conn) Discovery {
+	if d, ok := v.hosts[VirtualHost(conn)]; ok {
+		return d
+	}
+	return v.discovery
+}
@@ -16,6 +16,15 @@ type Animation interface {
 	Clear(conn *minecraft.Conn, serverGameData minecraft.GameData)
 }
 
+// Lifecycle may be implemented by an Animation to follow the progress of the transfer it is played for.
+type Lifecycle interface {
+	// Spawned is called once the player has spawned in the new server, right before the animation is cleared.
+	Spawned(conn *minecraft.Conn, serverGameData minecraft.GameData)
+	// Abort is called instead of Clear if the transfer fails after the animation was played, so that the
+	// animation can restore the player's screen, such as by resetting the camera.
+	Abort(conn *minecraft.Conn)
+}
+
 // NopAnimation is a no-operation implementation of the Animation interface.
 type NopAnimation struct{}
 
@@ -29,6 +38,14 @@ type cameraAnimation struct {
 	synced atomic.Bool
 }
 
+// Abort resets the player's camera, including the fades applied to it.
+func (animation *cameraAnimation) Abort(conn *minecraft.Conn) {
+	_ = conn.WritePacket(&packet.CameraInstruction{Clear: protocol.Option(true)})
+}
+
+// Spawned ...
+func (animation *cameraAnimation) Spawned(*minecraft.Conn, minecraft.GameData) {}
+
 func (animation *cameraAnimation) Sync(conn *minecraft.Conn) {
 	if animation.synced.CompareAndSwap(false, true) {
 		_ = conn.WritePacket(&packet.CameraPresets{
@@ -0,0 +1,181 @@
+package animation
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/sandertv/gophertunnel/minecraft"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+)
+
+// newTestConn returns the connection of a spawned client accepted by a listener without authentication, along
+// with the connection of the client reading the packets written to it.
+func newTestConn(t *testing.T) (conn *minecraft.Conn, client *minecraft.Conn) {
+	t.Helper()
+	listener, err := minecraft.ListenConfig{AuthenticationDisabled: true}.Listen("raknet", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	dialed := make(chan *minecraft.Conn, 1)
+	go func() {
+		conn, _ := (minecraft.Dialer{}).DialContext(ctx, "raknet", listener.Addr().String())
+		dialed <- conn
+	}()
+
+	accepted, err := listener.Accept()
+	if err != nil {
+		t.Fatalf("failed to accept client: %v", err)
+	}
+	conn = accepted.(*minecraft.Conn)
+	t.Cleanup(func() { _ = conn.Close() })
+	if err := conn.StartGame(minecraft.GameData{}); err != nil {
+		t.Fatalf("failed to start game: %v", err)
+	}
+
+	if client = <-dialed; client == nil {
+		t.Fatal("client did not spawn")
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	return conn, client
+}
+
+// readTitles reads the titles written to the client until one with the action is read, returning all of them.
+func readTitles(t *testing.T, client *minecraft.Conn, action int32) []packet.SetTitle {
+	t.Helper()
+	_ = client.SetReadDeadline(time.Now().Add(time.Second * 10))
+	var titles []packet.SetTitle
+	for {
+		pk, err := client.ReadPacket()
+		if err != nil {
+			t.Fatalf("failed to read packet: %v", err)
+		}
+
+		if title, ok := pk.(*packet.SetTitle); ok {
+			titles = append(titles, *title)
+			if title.ActionType == action {
+				return titles
+			}
+		}
+	}
+}
+
+func TestTitle(t *testing.T) {
+	tests := []struct {
+		name      string
+		animation *Title
+		want      []packet.SetTitle
+	}{
+		{
+			name:      "title",
+			animation: &Title{Title: "Connecting", FadeIn: time.Millisecond * 500, Stay: time.Second * 5, FadeOut: time.Second},
+			want: []packet.SetTitle{
+				{ActionType: packet.TitleActionSetDurations, FadeInDuration: 10, RemainDuration: 100, FadeOutDuration: 20},
+				{ActionType: packet.TitleActionSetTitle, Text: "Connecting"},
+			},
+		},
+		{
+			name:      "subtitle",
+			animation: &Title{Title: "Connecting", Subtitle: "BedWars", Stay: time.Second},
+			want: []packet.SetTitle{
+				{ActionType: packet.TitleActionSetDurations, RemainDuration: 20},
+				{ActionType: packet.TitleActionSetSubtitle, Text: "BedWars"},
+				{ActionType: packet.TitleActionSetTitle, Text: "Connecting"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, client := newTestConn(t)
+			tt.animation.Play(conn, minecraft.GameData{})
+			_ = conn.Flush()
+			if got := readTitles(t, client, packet.TitleActionSetTitle); !slices.Equal(got, tt.want) {
+				t.Fatalf("Play() sent %+v, want %+v", got, tt.want)
+			}
+
+			tt.animation.Abort(conn)
+			_ = conn.Flush()
+			if got := readTitles(t, client, packet.TitleActionClear); len(got) != 1 {
+				t.Fatalf("Abort() sent %+v, want a single clear", got)
+			}
+		})
+	}
+}
+
+// testAnimation is an animation recording the calls of its methods, prefixed by its name.
+type testAnimation struct {
+	name  string
+	calls *[]string
+}
+
+// Play ...
+func (a testAnimation) Play(*minecraft.Conn, minecraft.GameData) {
+	*a.calls = append(*a.calls, "play "+a.name)
+}
+
+// Clear ...
+func (a testAnimation) Clear(*minecraft.Conn, minecraft.GameData) {
+	*a.calls = append(*a.calls, "clear "+a.name)
+}
+
+// testLifecycleAnimation is a testAnimation also recording the calls of the methods of Lifecycle.
+type testLifecycleAnimation struct {
+	testAnimation
+}
+
+// Spawned ...
+func (a testLifecycleAnimation) Spawned(*minecraft.Conn, minecraft.GameData) {
+	*a.calls = append(*a.calls, "spawned "+a.name)
+}
+
+// Abort ...
+func (a testLifecycleAnimation) Abort(*minecraft.Conn) {
+	*a.calls = append(*a.calls, "abort "+a.name)
+}
+
+func TestComposite(t *testing.T) {
+	tests := []struct {
+		name string
+		call func(animation Composite)
+		want []string
+	}{
+		{
+			name: "play",
+			call: func(animation Composite) { animation.Play(nil, minecraft.GameData{}) },
+			want: []string{"play a", "play b", "play c"},
+		},
+		{
+			name: "clear",
+			call: func(animation Composite) { animation.Clear(nil, minecraft.GameData{}) },
+			want: []string{"clear c", "clear b", "clear a"},
+		},
+		{
+			name: "spawned",
+			call: func(animation Composite) { animation.Spawned(nil, minecraft.GameData{}) },
+			want: []string{"spawned a", "spawned c"},
+		},
+		{
+			name: "abort",
+			call: func(animation Composite) { animation.Abort(nil) },
+			want: []string{"abort c", "abort a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			tt.call(Composite{
+				testLifecycleAnimation{testAnimation{name: "a", calls: &calls}},
+				testAnimation{name: "b", calls: &calls},
+				testLifecycleAnimation{testAnimation{name: "c", calls: &calls}},
+			})
+			if !slices.Equal(calls, tt.want) {
+				t.Fatalf("calls = %v, want %v", calls, tt.want)
+			}
+		})
+	}
+}
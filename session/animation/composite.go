@@ -0,0 +1,44 @@
+package animation
+
+import (
+	"github.com/sandertv/gophertunnel/minecraft"
+)
+
+// Composite chains multiple animations, playing them in order and clearing them in reverse order, such as a
+// Fade combined with a Title.
+type Composite []Animation
+
+// Ensure that Composite satisfies the Animation interface.
+var _ Animation = Composite{}
+
+// Play ...
+func (animation Composite) Play(conn *minecraft.Conn, serverGameData minecraft.GameData) {
+	for _, a := range animation {
+		a.Play(conn, serverGameData)
+	}
+}
+
+// Clear ...
+func (animation Composite) Clear(conn *minecraft.Conn, serverGameData minecraft.GameData) {
+	for i := len(animation) - 1; i >= 0; i-- {
+		animation[i].Clear(conn, serverGameData)
+	}
+}
+
+// Spawned ...
+func (animation Composite) Spawned(conn *minecraft.Conn, serverGameData minecraft.GameData) {
+	for _, a := range animation {
+		if lifecycle, ok := a.(Lifecycle); ok {
+			lifecycle.Spawned(conn, serverGameData)
+		}
+	}
+}
+
+// Abort ...
+func (animation Composite) Abort(conn *minecraft.Conn) {
+	for i := len(animation) - 1; i >= 0; i-- {
+		if lifecycle, ok := animation[i].(Lifecycle); ok {
+			lifecycle.Abort(conn)
+		}
+	}
+}
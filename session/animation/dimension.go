@@ -9,6 +9,12 @@ import (
 // Dimension displays the dimension change screen to the player.
 type Dimension struct{}
 
+// Ensure that Dimension satisfies the Animation and Lifecycle interfaces.
+var (
+	_ Animation = &Dimension{}
+	_ Lifecycle = &Dimension{}
+)
+
 // Play ...
 func (animation *Dimension) Play(conn *minecraft.Conn, serverGameData minecraft.GameData) {
 	var dimension int32
@@ -26,6 +32,15 @@ func (animation *Dimension) Clear(conn *minecraft.Conn, serverGameData minecraft
 	sendDimension(conn, serverGameData, packet.DimensionOverworld, true)
 }
 
+// Abort ...
+func (animation *Dimension) Abort(conn *minecraft.Conn) {
+	// Play changed the dimension away from the one of the client's game data, which the player is moved back to.
+	sendDimension(conn, conn.GameData(), conn.GameData().Dimension, true)
+}
+
+// Spawned ...
+func (animation *Dimension) Spawned(*minecraft.Conn, minecraft.GameData) {}
+
 // sendDimension updates the player's dimension and optionally force-spawns them if playStatus is enabled.
 func sendDimension(conn *minecraft.Conn, serverGameData minecraft.GameData, dimension int32, playStatus bool) {
 	_ = conn.WritePacket(&packet.ChangeDimension{Dimension: dimension, Position: serverGameData.PlayerPosition})
@@ -27,3 +27,11 @@ func (animation *Fade) Play(conn *minecraft.Conn, _ minecraft.GameData) {
 		}),
 	})
 }
+
+// Spawned ...
+func (animation *Fade) Spawned(*minecraft.Conn, minecraft.GameData) {}
+
+// Abort ...
+func (animation *Fade) Abort(conn *minecraft.Conn) {
+	_ = conn.WritePacket(&packet.CameraInstruction{Clear: protocol.Option(true)})
+}
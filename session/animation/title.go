@@ -0,0 +1,58 @@
+package animation
+
+import (
+	"time"
+
+	"github.com/sandertv/gophertunnel/minecraft"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+)
+
+// Title represents a lightweight animation displaying a title and an optional subtitle, such as
+// "Connecting to BedWars...", while the player is transferred. The title is cleared once the player has
+// spawned in the new server, so Stay should be long enough to last for the whole transfer.
+type Title struct {
+	Title    string
+	Subtitle string
+
+	FadeIn  time.Duration
+	Stay    time.Duration
+	FadeOut time.Duration
+}
+
+// Ensure that Title satisfies the Animation and Lifecycle interfaces.
+var (
+	_ Animation = &Title{}
+	_ Lifecycle = &Title{}
+)
+
+// Play ...
+func (animation *Title) Play(conn *minecraft.Conn, _ minecraft.GameData) {
+	_ = conn.WritePacket(&packet.SetTitle{
+		ActionType:      packet.TitleActionSetDurations,
+		FadeInDuration:  ticks(animation.FadeIn),
+		RemainDuration:  ticks(animation.Stay),
+		FadeOutDuration: ticks(animation.FadeOut),
+	})
+	if animation.Subtitle != "" {
+		_ = conn.WritePacket(&packet.SetTitle{ActionType: packet.TitleActionSetSubtitle, Text: animation.Subtitle})
+	}
+	_ = conn.WritePacket(&packet.SetTitle{ActionType: packet.TitleActionSetTitle, Text: animation.Title})
+}
+
+// Clear ...
+func (animation *Title) Clear(conn *minecraft.Conn, _ minecraft.GameData) {
+	_ = conn.WritePacket(&packet.SetTitle{ActionType: packet.TitleActionClear})
+}
+
+// Spawned ...
+func (animation *Title) Spawned(*minecraft.Conn, minecraft.GameData) {}
+
+// Abort ...
+func (animation *Title) Abort(conn *minecraft.Conn) {
+	animation.Clear(conn, minecraft.GameData{})
+}
+
+// ticks converts the duration to game ticks, each lasting 50 milliseconds.
+func ticks(duration time.Duration) int32 {
+	return int32(duration / (time.Millisecond * 50))
+}
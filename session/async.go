@@ -0,0 +1,169 @@
+package session
+
+import (
+	"bytes"
+	"slices"
+	"sync"
+
+	"github.com/sandertv/gophertunnel/minecraft"
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+)
+
+// AsyncProcessor is a Processor running another processor on a bounded pool of workers, keeping heavyweight
+// processing such as database lookups or webhook calls off the goroutines forwarding packets. Actions are
+// queued for the workers, and once the queue is full, the session waits for a worker to become available
+// rather than letting the queue grow without bounds. Lightweight processors that filter packets should be
+// added to the session synchronously alongside it.
+//
+// As the processor runs after the action has been performed, it cannot cancel actions or modify packets, and it
+// must not modify the values passed to it. Actions are only processed in order if a single worker is used.
+// Decoded packets are copied for the workers, and packets that cannot be copied are not processed.
+type AsyncProcessor struct {
+	processor  Processor
+	jobs       chan func()
+	serverPool packet.Pool
+	clientPool packet.Pool
+
+	closed chan struct{}
+	once   sync.Once
+	wg     sync.WaitGroup
+}
+
+// NewAsyncProcessor creates a new AsyncProcessor running the processor on the provided amount of workers, with
+// room for queueSize actions waiting for a worker.
+func NewAsyncProcessor(processor Processor, workers, queueSize int) *AsyncProcessor {
+	p := &AsyncProcessor{
+		processor:  processor,
+		jobs:       make(chan func(), queueSize),
+		serverPool: packet.NewServerPool(),
+		clientPool: packet.NewClientPool(),
+		closed:     make(chan struct{}),
+	}
+	for range max(workers, 1) {
+		p.wg.Add(1)
+		go p.work()
+	}
+	return p
+}
+
+// Close stops the workers of the processor, discarding the actions still queued. Actions performed after the
+// processor was closed are not processed.
+func (p *AsyncProcessor) Close() {
+	p.once.Do(func() {
+		close(p.closed)
+		p.wg.Wait()
+	})
+}
+
+// work runs the queued actions until the processor is closed.
+func (p *AsyncProcessor) work() {
+	defer p.wg.Done()
+	for {
+		select {
+		case <-p.closed:
+			return
+		case job := <-p.jobs:
+			job()
+		}
+	}
+}
+
+// submit queues the job, blocking until there is room for it in the queue or the processor is closed.
+func (p *AsyncProcessor) submit(job func()) {
+	select {
+	case <-p.closed:
+	case p.jobs <- job:
+	}
+}
+
+// clonePacket returns a deep copy of the packet by encoding and decoding it using the pool, so that the workers
+// do not share the packets the session keeps using. False is returned if the packet could not be copied.
+func clonePacket(pk packet.Packet, pool packet.Pool) (clone packet.Packet, ok bool) {
+	factory, ok := pool[pk.ID()]
+	if !ok {
+		return nil, false
+	}
+
+	defer func() {
+		if recover() != nil {
+			clone, ok = nil, false
+		}
+	}()
+	// Both ends use the same shield ID, which only determines data the reader discards.
+	buf := bytes.NewBuffer(make([]byte, 0, 64))
+	pk.Marshal(protocol.NewWriter(buf, 0))
+	clone = factory()
+	clone.Marshal(protocol.NewReader(buf, 0, false))
+	return clone, true
+}
+
+// Ensure that AsyncProcessor satisfies the Processor and Subscriber interfaces.
+var (
+	_ Processor  = &AsyncProcessor{}
+	_ Subscriber = &AsyncProcessor{}
+)
+
+// Subscribed ...
+func (p *AsyncProcessor) Subscribed() []uint32 {
+	if subscriber, ok := p.processor.(Subscriber); ok {
+		return subscriber.Subscribed()
+	}
+	return nil
+}
+
+func (p *AsyncProcessor) ProcessStartGame(_ *Context, data *minecraft.GameData) {
+	gameData := *data
+	p.submit(func() { p.processor.ProcessStartGame(NewContext(), &gameData) })
+}
+
+func (p *AsyncProcessor) ProcessServer(_ *Context, pk *packet.Packet) {
+	if v, ok := clonePacket(*pk, p.serverPool); ok {
+		p.submit(func() { p.processor.ProcessServer(NewContext(), &v) })
+	}
+}
+
+func (p *AsyncProcessor) ProcessServerEncoded(_ *Context, pk *[]byte) {
+	payload := slices.Clone(*pk)
+	p.submit(func() { p.processor.ProcessServerEncoded(NewContext(), &payload) })
+}
+
+func (p *AsyncProcessor) ProcessClient(_ *Context, pk *packet.Packet) {
+	if v, ok := clonePacket(*pk, p.clientPool); ok {
+		p.submit(func() { p.processor.ProcessClient(NewContext(), &v) })
+	}
+}
+
+func (p *AsyncProcessor) ProcessClientEncoded(_ *Context, pk *[]byte) {
+	payload := slices.Clone(*pk)
+	p.submit(func() { p.processor.ProcessClientEncoded(NewContext(), &payload) })
+}
+
+func (p *AsyncProcessor) ProcessFlush(_ *Context) {
+	p.submit(func() { p.processor.ProcessFlush(NewContext()) })
+}
+
+func (p *AsyncProcessor) ProcessPreTransfer(_ *Context, origin *string, target *string) {
+	o, t := *origin, *target
+	p.submit(func() { p.processor.ProcessPreTransfer(NewContext(), &o, &t) })
+}
+
+func (p *AsyncProcessor) ProcessTransferFailure(_ *Context, origin *string, target *string, err error) {
+	o, t := *origin, *target
+	p.submit(func() { p.processor.ProcessTransferFailure(NewContext(), &o, &t, err) })
+}
+
+func (p *AsyncProcessor) ProcessPostTransfer(_ *Context, origin *string, target *string) {
+	o, t := *origin, *target
+	p.submit(func() { p.processor.ProcessPostTransfer(NewContext(), &o, &t) })
+}
+
+func (p *AsyncProcessor) ProcessCache(_ *Context, new *[]byte) {
+	cache := slices.Clone(*new)
+	p.submit(func() { p.processor.ProcessCache(NewContext(), &cache) })
+}
+
+func (p *AsyncProcessor) ProcessDisconnection(_ *Context, message *string) {
+	m := *message
+	p.submit(func() { p.processor.ProcessDisconnection(NewContext(), &m) })
+}
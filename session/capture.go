@@ -0,0 +1,103 @@
+package session
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/sandertv/gophertunnel/minecraft"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+)
+
+const (
+	// DirectionClient is the direction of packets sent by the client to the server.
+	DirectionClient = iota
+	// DirectionServer is the direction of packets sent by the server to the client.
+	DirectionServer
+)
+
+// captureHeaderSize is the size of the header preceding every captured packet:
+// a direction byte, a timestamp in nanoseconds and the length of the payload.
+const captureHeaderSize = 1 + 8 + 4
+
+// CapturedPacket is a packet recorded by Session.StartCapture.
+type CapturedPacket struct {
+	// Direction is the direction the packet was travelling in, either DirectionClient or DirectionServer.
+	Direction int
+	// Time is the time at which the packet passed through the proxy.
+	Time time.Time
+	// Payload is the encoded packet, including its packet.Header.
+	Payload []byte
+}
+
+// CaptureReader reads packets recorded by Session.StartCapture.
+type CaptureReader struct {
+	r      io.Reader
+	header []byte
+}
+
+// NewCaptureReader creates a new CaptureReader reading from the given io.Reader.
+func NewCaptureReader(r io.Reader) *CaptureReader {
+	return &CaptureReader{
+		r:      r,
+		header: make([]byte, captureHeaderSize),
+	}
+}
+
+// ReadPacket reads the next captured packet. It returns io.EOF once the capture has been fully read.
+func (r *CaptureReader) ReadPacket() (CapturedPacket, error) {
+	if _, err := io.ReadFull(r.r, r.header); err != nil {
+		return CapturedPacket{}, err
+	}
+
+	payload := make([]byte, binary.BigEndian.Uint32(r.header[9:]))
+	if _, err := io.ReadFull(r.r, payload); err != nil {
+		return CapturedPacket{}, fmt.Errorf("failed to read captured packet: %w", err)
+	}
+	return CapturedPacket{
+		Direction: int(r.header[0]),
+		Time:      time.Unix(0, int64(binary.BigEndian.Uint64(r.header[1:]))),
+		Payload:   payload,
+	}, nil
+}
+
+// capture writes packets passing through a session to an io.Writer. Every packet is prefixed
+// with its direction, a timestamp and its length.
+type capture struct {
+	w      io.Writer
+	header []byte
+	mu     sync.Mutex
+}
+
+func newCapture(w io.Writer) *capture {
+	return &capture{
+		w:      w,
+		header: make([]byte, captureHeaderSize),
+	}
+}
+
+// write writes the payload with the given direction to the underlying io.Writer.
+func (c *capture) write(direction int, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.header[0] = byte(direction)
+	binary.BigEndian.PutUint64(c.header[1:], uint64(time.Now().UnixNano()))
+	binary.BigEndian.PutUint32(c.header[9:], uint32(len(payload)))
+	if _, err := c.w.Write(c.header); err != nil {
+		return err
+	}
+	_, err := c.w.Write(payload)
+	return err
+}
+
+// encodePacket encodes a decoded packet, including its header, using the given protocol.
+func encodePacket(proto minecraft.Protocol, pk packet.Packet, shieldID int32) []byte {
+	buf := bytes.NewBuffer(make([]byte, 0, 64))
+	header := &packet.Header{PacketID: pk.ID()}
+	_ = header.Write(buf)
+	pk.Marshal(proto.NewWriter(buf, shieldID))
+	return buf.Bytes()
+}
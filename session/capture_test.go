@@ -0,0 +1,66 @@
+package session
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestCaptureRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		packets []CapturedPacket
+	}{
+		{name: "empty"},
+		{name: "single", packets: []CapturedPacket{{Direction: DirectionClient, Payload: []byte{0x01, 0x02}}}},
+		{
+			name: "order and direction",
+			packets: []CapturedPacket{
+				{Direction: DirectionClient, Payload: []byte{0x01}},
+				{Direction: DirectionServer, Payload: []byte{0x02, 0x03}},
+				{Direction: DirectionServer, Payload: []byte{}},
+				{Direction: DirectionClient, Payload: bytes.Repeat([]byte{0x04}, 1024)},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			c := newCapture(buf)
+			for _, pk := range tt.packets {
+				if err := c.write(pk.Direction, pk.Payload); err != nil {
+					t.Fatalf("write() error = %v", err)
+				}
+			}
+
+			r := NewCaptureReader(buf)
+			for i, want := range tt.packets {
+				got, err := r.ReadPacket()
+				if err != nil {
+					t.Fatalf("ReadPacket() #%v error = %v", i, err)
+				}
+				if got.Direction != want.Direction || !bytes.Equal(got.Payload, want.Payload) {
+					t.Fatalf("ReadPacket() #%v = %v %x, want %v %x", i, got.Direction, got.Payload, want.Direction, want.Payload)
+				}
+				if got.Time.IsZero() {
+					t.Fatalf("ReadPacket() #%v has no timestamp", i)
+				}
+			}
+			if _, err := r.ReadPacket(); !errors.Is(err, io.EOF) {
+				t.Fatalf("ReadPacket() error = %v, want %v", err, io.EOF)
+			}
+		})
+	}
+}
+
+func TestCaptureReaderTruncated(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := newCapture(buf).write(DirectionServer, []byte{0x01, 0x02, 0x03}); err != nil {
+		t.Fatalf("write() error = %v", err)
+	}
+	buf.Truncate(buf.Len() - 1)
+	if _, err := NewCaptureReader(buf).ReadPacket(); err == nil || errors.Is(err, io.EOF) {
+		t.Fatalf("ReadPacket() error = %v, want an unexpected EOF", err)
+	}
+}
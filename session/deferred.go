@@ -0,0 +1,53 @@
+package session
+
+import (
+	"sync"
+
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+)
+
+// deferredPacketLimit is the maximum amount of packets deferred while a transfer is in progress. Packets written
+// once the limit is reached are dropped.
+const deferredPacketLimit = 512
+
+// deferredPackets holds the packets written using Session.WritePacket while a transfer is in progress.
+type deferredPackets struct {
+	packets []packet.Packet
+	mu      sync.Mutex
+}
+
+// WritePacket writes a packet to the client. Unlike writing to the client connection directly, packets written
+// while a transfer is in progress are deferred until the player has spawned in the new server, or the transfer has
+// failed, so that they never interleave with the transfer sequence and appear on the wrong server.
+func (s *Session) WritePacket(pk packet.Packet) error {
+	d := &s.deferred
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	// Packets are still deferred if there are pending ones, as the transfer has just finished and they are
+	// about to be flushed, which keeps packets in the order they were written.
+	if s.TransferState().InProgress() || len(d.packets) > 0 {
+		if len(d.packets) < deferredPacketLimit {
+			d.packets = append(d.packets, pk)
+		} else {
+			s.logger.Debug("dropped deferred packet", "id", pk.ID())
+		}
+		return nil
+	}
+	return s.client.WritePacket(pk)
+}
+
+// flushDeferred writes the packets deferred during the last transfer to the client.
+func (s *Session) flushDeferred() {
+	d := &s.deferred
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.packets) == 0 {
+		return
+	}
+
+	for _, pk := range d.packets {
+		_ = s.client.WritePacket(pk)
+	}
+	d.packets = nil
+	_ = s.client.Flush()
+}
@@ -0,0 +1,59 @@
+package session
+
+// Event is an event of a session's lifecycle, dispatched to the functions subscribed to the session's Registry
+// using Registry.Subscribe. It is one of EventLogin, EventPreTransfer, EventPostTransfer or EventDisconnect.
+type Event interface {
+	// Session returns the session the event occurred on.
+	Session() *Session
+}
+
+// EventLogin is dispatched once a session has logged in, either to a server or to limbo.
+type EventLogin struct {
+	session *Session
+}
+
+// Session ...
+func (e EventLogin) Session() *Session {
+	return e.session
+}
+
+// EventPreTransfer is dispatched once a transfer of a session starts, after the processor has allowed it.
+type EventPreTransfer struct {
+	session *Session
+	// Origin is the address of the server the session is transferred from.
+	Origin string
+	// Target is the address of the server the session is transferred to.
+	Target string
+}
+
+// Session ...
+func (e EventPreTransfer) Session() *Session {
+	return e.session
+}
+
+// EventPostTransfer is dispatched once a transfer of a session has completed.
+type EventPostTransfer struct {
+	session *Session
+	// Origin is the address of the server the session was transferred from.
+	Origin string
+	// Target is the address of the server the session was transferred to.
+	Target string
+}
+
+// Session ...
+func (e EventPostTransfer) Session() *Session {
+	return e.session
+}
+
+// EventDisconnect is dispatched once a session that logged in has been closed. It is not dispatched for sessions
+// closed before EventLogin was dispatched for them.
+type EventDisconnect struct {
+	session *Session
+	// Err is the error the session was closed with.
+	Err error
+}
+
+// Session ...
+func (e EventDisconnect) Session() *Session {
+	return e.session
+}
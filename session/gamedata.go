@@ -0,0 +1,41 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/sandertv/gophertunnel/minecraft"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+)
+
+// ErrInvalidGameData is returned when the game data sent by a server is unusable, as passing it on to the client
+// could crash or hang it.
+var ErrInvalidGameData = errors.New("invalid game data")
+
+// validateGameData validates the fields of the game data required by the client, returning an error wrapping
+// ErrInvalidGameData if any of them is invalid.
+func validateGameData(gameData minecraft.GameData) error {
+	if gameData.EntityRuntimeID == 0 {
+		return fmt.Errorf("%w: missing entity runtime id", ErrInvalidGameData)
+	}
+
+	switch gameData.Dimension {
+	case packet.DimensionOverworld, packet.DimensionNether, packet.DimensionEnd:
+	default:
+		return fmt.Errorf("%w: unknown dimension %v", ErrInvalidGameData, gameData.Dimension)
+	}
+
+	// The world spawn is always located in the overworld, whichever dimension the player spawns in. A world spawn
+	// Y of math.MaxInt16 is used by servers to spawn the player on the highest block.
+	minY, maxY := dimensionRange(packet.DimensionOverworld)
+	if y := float32(gameData.WorldSpawn.Y()); (y < minY || y > maxY) && y != math.MaxInt16 {
+		return fmt.Errorf("%w: world spawn %v is out of the world bounds", ErrInvalidGameData, gameData.WorldSpawn)
+	}
+
+	position := &transferPosition{pos: gameData.PlayerPosition, yaw: gameData.Yaw, pitch: gameData.Pitch}
+	if !position.finite() {
+		return fmt.Errorf("%w: invalid player position %v", ErrInvalidGameData, gameData.PlayerPosition)
+	}
+	return nil
+}
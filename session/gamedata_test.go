@@ -0,0 +1,66 @@
+package session
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/sandertv/gophertunnel/minecraft"
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+)
+
+func TestValidateGameData(t *testing.T) {
+	valid := minecraft.GameData{
+		EntityRuntimeID: 1,
+		Dimension:       packet.DimensionOverworld,
+		WorldSpawn:      protocol.BlockPos{0, 64, 0},
+		PlayerPosition:  mgl32.Vec3{0, 64, 0},
+	}
+	tests := []struct {
+		name    string
+		modify  func(gameData *minecraft.GameData)
+		wantErr bool
+	}{
+		{name: "valid", modify: func(*minecraft.GameData) {}},
+		{name: "missing entity runtime id", modify: func(gameData *minecraft.GameData) {
+			gameData.EntityRuntimeID = 0
+		}, wantErr: true},
+		{name: "unknown dimension", modify: func(gameData *minecraft.GameData) {
+			gameData.Dimension = 3
+		}, wantErr: true},
+		{name: "world spawn below the overworld", modify: func(gameData *minecraft.GameData) {
+			gameData.WorldSpawn = protocol.BlockPos{0, -65, 0}
+		}, wantErr: true},
+		{name: "world spawn above the overworld", modify: func(gameData *minecraft.GameData) {
+			gameData.WorldSpawn = protocol.BlockPos{0, 321, 0}
+		}, wantErr: true},
+		{name: "world spawn on the highest block", modify: func(gameData *minecraft.GameData) {
+			gameData.WorldSpawn = protocol.BlockPos{0, math.MaxInt16, 0}
+		}},
+		{name: "overworld spawn while in the nether", modify: func(gameData *minecraft.GameData) {
+			gameData.Dimension = packet.DimensionNether
+			gameData.WorldSpawn = protocol.BlockPos{0, 200, 0}
+		}},
+		{name: "infinite player position", modify: func(gameData *minecraft.GameData) {
+			gameData.PlayerPosition = mgl32.Vec3{float32(math.Inf(1)), 64, 0}
+		}, wantErr: true},
+		{name: "invalid pitch", modify: func(gameData *minecraft.GameData) {
+			gameData.Pitch = float32(math.NaN())
+		}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gameData := valid
+			tt.modify(&gameData)
+			err := validateGameData(gameData)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateGameData() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidGameData) {
+				t.Fatalf("validateGameData() error = %v, want %v", err, ErrInvalidGameData)
+			}
+		})
+	}
+}
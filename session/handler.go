@@ -5,15 +5,27 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
 	"slices"
+	"strconv"
 	"time"
 
+	"github.com/cooldogedev/spectrum/server"
 	spectrumpacket "github.com/cooldogedev/spectrum/server/packet"
+	"github.com/cooldogedev/spectrum/util"
+	"github.com/sandertv/gophertunnel/minecraft"
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
 	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
 )
 
 // handleServer continuously reads packets from the server and forwards them to the client.
 func handleServer(s *Session) {
+	defer s.wg.Done()
+	var (
+		malformedConn  *server.Conn
+		malformedCount int
+	)
+
 loop:
 	for {
 		select {
@@ -23,21 +35,63 @@ loop:
 		default:
 		}
 
-		server := s.Server()
-		pk, err := server.ReadPacket()
+		conn := s.Server()
+		if conn == nil {
+			// The session is held in limbo without a server, wait for a transfer to provide one.
+			select {
+			case <-s.ctx.Done():
+			case <-time.After(time.Millisecond * 50):
+			}
+			continue loop
+		}
+
+		pk, err := conn.ReadPacket()
 		if err != nil {
-			if server != s.Server() {
+			if conn != s.Server() {
+				continue loop
+			}
+
+			if s.inLimbo.Load() {
+				conn.CloseWithError(fmt.Errorf("failed to read packet from server: %w", err))
+				s.clearServer(conn)
 				continue loop
 			}
 
-			server.CloseWithError(fmt.Errorf("failed to read packet from server: %w", err))
+			if errors.Is(err, server.ErrMalformedPacket) {
+				if malformedConn != conn {
+					malformedConn, malformedCount = conn, 0
+				}
+
+				malformedCount++
+				if malformedCount <= s.opts.Load().MaxBackendPacketErrors {
+					logError(s, "skipped malformed packet from server", err)
+					continue loop
+				}
+			}
+
+			conn.CloseWithError(fmt.Errorf("failed to read packet from server: %w", err))
 			if err := s.fallback(); err != nil {
-				s.CloseWithError(fmt.Errorf("fallback failed: %w", err))
+				if s.opts.Load().LimboOnNoBackend {
+					logError(s, "fallback failed, holding session in limbo", err)
+					s.holdInLimbo(conn)
+					continue loop
+				}
+				s.serverMu.RLock()
+				addr := s.serverAddr
+				s.serverMu.RUnlock()
+				s.CloseWithError(withMessage(util.MessageFallbackFailed, addr, fmt.Errorf("fallback failed: %w", err)))
 				break loop
 			}
 			continue loop
 		}
 
+		if conn != s.Server() {
+			// The connection was replaced by a transfer while the packet was being read, packets
+			// still arriving from the previous server are discarded so they never reach the client
+			// after the new server's spawn.
+			continue loop
+		}
+
 		switch pk := pk.(type) {
 		case *spectrumpacket.Flush:
 			ctx := NewContext()
@@ -53,25 +107,44 @@ loop:
 			}
 		case *spectrumpacket.Latency:
 			s.latency.Store(pk.Latency)
+			s.stats.answer(pk.Latency)
+			s.latencies.record(s.Latency())
 		case *spectrumpacket.Transfer:
-			if err := s.Transfer(pk.Addr); err != nil {
-				logError(s, "failed to transfer", err)
+			// The transfer is scheduled rather than performed here, so that reading from the server is not
+			// blocked while the target server is dialed.
+			s.ScheduleTransfer(pk.Addr)
+		case *spectrumpacket.TransferGroup:
+			if err := s.TransferToGroup(pk.Group); err != nil {
+				logError(s, "failed to transfer to group", err)
 			}
 		case *spectrumpacket.UpdateCache:
 			s.SetCache(pk.Cache)
 		case packet.Packet:
-			if err := handleServerPacket(s, pk); err != nil {
+			if err := handleServerPacket(s, conn, pk); err != nil {
 				s.CloseWithError(fmt.Errorf("failed to write packet to client: %w", err))
 				logError(s, "failed to write packet to client", err)
 				break loop
 			}
 		case []byte:
+			if c := s.capture.Load(); c != nil {
+				s.capturePacket(c, DirectionServer, pk)
+			}
+
+			if r := s.entityRemap(); r != nil {
+				var proto minecraft.Protocol = minecraft.DefaultProtocol
+				if s.opts.Load().SyncProtocol {
+					proto = s.client.Proto()
+				}
+				pk = r.applyRaw(proto, pk, conn.ShieldID(), false)
+			}
+
 			ctx := NewContext()
 			s.Processor().ProcessServerEncoded(ctx, &pk)
 			if ctx.Cancelled() {
 				continue loop
 			}
 
+			s.tracker.handleRaw(s, conn.ShieldID(), pk)
 			if _, err := s.client.Write(pk); err != nil {
 				s.CloseWithError(fmt.Errorf("failed to write packet to client: %w", err))
 				logError(s, "failed to write packet to client", err)
@@ -83,6 +156,7 @@ loop:
 
 // handleClient continuously reads packets from the client and forwards them to the server.
 func handleClient(s *Session) {
+	defer s.wg.Done()
 	header := &packet.Header{}
 	pool := s.client.Proto().Packets(true)
 	var shieldID int32
@@ -109,8 +183,18 @@ loop:
 			break loop
 		}
 
-		if err := handleClientPacket(s, header, pool, shieldID, payload); err != nil {
-			s.Server().CloseWithError(fmt.Errorf("failed to write packet to server: %w", err))
+		if c := s.capture.Load(); c != nil {
+			s.capturePacket(c, DirectionClient, payload)
+		}
+
+		// The server is loaded once, as it may be cleared or replaced while the packet is being handled.
+		conn := s.Server()
+		if conn == nil {
+			continue loop
+		}
+
+		if err := handleClientPacket(s, conn, header, pool, shieldID, payload); err != nil {
+			conn.CloseWithError(fmt.Errorf("failed to write packet to server: %w", err))
 		}
 	}
 }
@@ -118,7 +202,9 @@ loop:
 // handleLatency periodically sends the client's current ping and timestamp to the server for latency reporting.
 // The client's latency is derived from half of RakNet's round-trip time (RTT).
 // To calculate the total latency, we multiply this value by 2.
-func handleLatency(s *Session, interval int64) {
+func handleLatency(s *Session) {
+	defer s.wg.Done()
+	interval := s.opts.Load().LatencyInterval
 	ticker := time.NewTicker(time.Millisecond * time.Duration(interval))
 	defer ticker.Stop()
 loop:
@@ -128,7 +214,18 @@ loop:
 			s.CloseWithError(context.Cause(s.ctx))
 			break loop
 		case <-ticker.C:
-			if err := s.Server().WritePacket(&spectrumpacket.Latency{Latency: s.client.Latency().Milliseconds() * 2, Timestamp: time.Now().UnixMilli()}); err != nil {
+			if latest := s.opts.Load().LatencyInterval; latest > 0 && latest != interval {
+				interval = latest
+				ticker.Reset(time.Millisecond * time.Duration(interval))
+			}
+
+			conn := s.Server()
+			if conn == nil {
+				continue loop
+			}
+
+			s.stats.probe()
+			if err := conn.WritePacket(&spectrumpacket.Latency{Latency: s.client.Latency().Milliseconds() * 2, Timestamp: time.Now().UnixMilli()}); err != nil {
 				logError(s, "failed to write latency packet", err)
 			}
 		}
@@ -136,14 +233,48 @@ loop:
 }
 
 // handleServerPacket processes and forwards the provided packet from the server to the client.
-func handleServerPacket(s *Session, pk packet.Packet) (err error) {
+func handleServerPacket(s *Session, conn *server.Conn, pk packet.Packet) (err error) {
+	if c := s.capture.Load(); c != nil {
+		var proto minecraft.Protocol = minecraft.DefaultProtocol
+		if s.opts.Load().SyncProtocol {
+			proto = s.client.Proto()
+		}
+		s.capturePacket(c, DirectionServer, encodePacket(proto, pk, conn.ShieldID()))
+	}
+
+	if r := s.entityRemap(); r != nil {
+		r.apply(pk)
+	}
+
 	ctx := NewContext()
 	s.Processor().ProcessServer(ctx, &pk)
 	if ctx.Cancelled() {
 		return
 	}
 
-	if s.opts.SyncProtocol {
+	if pk, ok := pk.(*packet.Transfer); ok && s.opts.Load().InterceptServerTransfer {
+		s.ScheduleTransfer(net.JoinHostPort(pk.Address, strconv.Itoa(int(pk.Port))))
+		return
+	}
+
+	if pk, ok := pk.(*packet.Disconnect); ok {
+		if s.opts.Load().RelayServerDisconnect {
+			// The server disconnected the player intentionally, the session is closed with the server's reason
+			// rather than falling back once the server closes the connection.
+			s.Disconnect(pk.Message)
+			return
+		}
+		// The client would leave if the packet was forwarded, the session falls back instead once the server
+		// closes the connection.
+		s.logger.Debug("server disconnected session", "message", pk.Message)
+		return
+	}
+
+	if pk, ok := pk.(*packet.SetActorData); ok {
+		s.mergeMetadata(pk)
+	}
+
+	if s.opts.Load().SyncProtocol {
 		for _, latest := range s.client.Proto().ConvertToLatest(pk, s.client) {
 			s.tracker.handlePacket(latest)
 		}
@@ -153,18 +284,31 @@ func handleServerPacket(s *Session, pk packet.Packet) (err error) {
 	return s.client.WritePacket(pk)
 }
 
-// handleClientPacket processes and forwards the provided packet from the client to the server.
-func handleClientPacket(s *Session, header *packet.Header, pool packet.Pool, shieldID int32, payload []byte) (err error) {
+// handleClientPacket processes and forwards the provided packet from the client to the server connection.
+func handleClientPacket(s *Session, conn *server.Conn, header *packet.Header, pool packet.Pool, shieldID int32, payload []byte) (err error) {
 	ctx := NewContext()
 	buf := bytes.NewBuffer(payload)
 	if err := header.Read(buf); err != nil {
 		return errors.New("failed to decode header")
 	}
 
-	if !slices.Contains(s.opts.ClientDecode, header.PacketID) {
+	if header.PacketID == packet.IDModalFormResponse {
+		var formID uint32
+		if err := protocol.Varuint32(bytes.NewReader(buf.Bytes()), &formID); err == nil && !s.tracker.answerForm(formID) {
+			// The form was sent by the previous server and closed during the transfer, the response is not
+			// forwarded to a server that does not expect it.
+			s.logger.Debug("dropped response to a form of the previous server", "id", formID)
+			return nil
+		}
+	}
+
+	if !slices.Contains(s.opts.Load().ClientDecode, header.PacketID) || !s.decodeRequired(header.PacketID) {
 		s.Processor().ProcessClientEncoded(ctx, &payload)
 		if !ctx.Cancelled() {
-			return s.Server().Write(payload)
+			if r := s.entityRemap(); r != nil {
+				payload = r.applyRaw(s.client.Proto(), payload, shieldID, true)
+			}
+			return conn.Write(payload)
 		}
 		return
 	}
@@ -177,17 +321,21 @@ func handleClientPacket(s *Session, header *packet.Header, pool packet.Pool, shi
 
 	factory, ok := pool[header.PacketID]
 	if !ok {
-		return fmt.Errorf("unknown packet %d", header.PacketID)
+		return handleUnknownClientPacket(s, conn, header.PacketID, payload)
 	}
 
 	pk := factory()
 	pk.Marshal(s.client.Proto().NewReader(buf, shieldID, true))
-	if s.opts.SyncProtocol {
+	if s.opts.Load().SyncProtocol {
 		s.Processor().ProcessClient(ctx, &pk)
 		if ctx.Cancelled() {
 			return
 		}
-		return s.Server().WritePacket(pk)
+
+		if r := s.entityRemap(); r != nil {
+			r.apply(pk)
+		}
+		return conn.WritePacket(pk)
 	}
 
 	for _, latest := range s.client.Proto().ConvertToLatest(pk, s.client) {
@@ -196,13 +344,43 @@ func handleClientPacket(s *Session, header *packet.Header, pool packet.Pool, shi
 			break
 		}
 
-		if err := s.Server().WritePacket(latest); err != nil {
+		if r := s.entityRemap(); r != nil {
+			r.apply(latest)
+		}
+		if err := conn.WritePacket(latest); err != nil {
 			return err
 		}
 	}
 	return
 }
 
+// decodeRequired reports whether a client packet with the provided ID listed in util.Opts.ClientDecode has to be
+// decoded. Decoding is skipped, forwarding the packet as is, if no processor of the session processes it and
+// the packet does not need to be converted to the protocol used with the server.
+func (s *Session) decodeRequired(id uint32) bool {
+	if processes(s.Processor(), id) {
+		return true
+	}
+	return !s.opts.Load().SyncProtocol && s.client.Proto().ID() != minecraft.DefaultProtocol.ID()
+}
+
+// handleUnknownClientPacket handles a packet from the client that could not be decoded according to the
+// configured util.Opts.UnknownClientPacketPolicy.
+func handleUnknownClientPacket(s *Session, conn *server.Conn, id uint32, payload []byte) error {
+	switch s.opts.Load().UnknownClientPacketPolicy {
+	case util.UnknownClientPacketPolicyForwardRaw:
+		return conn.Write(payload)
+	case util.UnknownClientPacketPolicyDrop:
+		s.logger.Debug("dropped unknown packet from client", "id", id)
+		return nil
+	case util.UnknownClientPacketPolicyDisconnect:
+		s.CloseWithError(fmt.Errorf("unknown packet %d", id))
+		return nil
+	default:
+		return fmt.Errorf("unknown packet %d", id)
+	}
+}
+
 func logError(s *Session, msg string, err error) {
 	select {
 	case <-s.ctx.Done():
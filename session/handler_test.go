@@ -0,0 +1,278 @@
+package session
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cooldogedev/spectrum/protocol"
+	"github.com/cooldogedev/spectrum/util"
+	"github.com/golang/snappy"
+	"github.com/sandertv/gophertunnel/minecraft"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+)
+
+func TestHandleServerPacketTransfer(t *testing.T) {
+	tests := []struct {
+		name      string
+		host      string
+		intercept bool
+		wantAddr  string
+	}{
+		{name: "intercepted", host: "127.0.0.1", intercept: true, wantAddr: "127.0.0.1:19133"},
+		{name: "intercepted ipv6", host: "::1", intercept: true, wantAddr: "[::1]:19133"},
+		{name: "forwarded", host: "127.0.0.1", wantAddr: "origin"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := *util.DefaultOpts()
+			opts.InterceptServerTransfer = tt.intercept
+			opts.TransferCooldown = 0
+			s, client := newTestSession(t, opts, testTransport{serve: testBackend(testServerGameData, nil)})
+			if _, err := transferPackets(t, s, client, func() error { return s.Transfer("origin") }); err != nil {
+				t.Fatalf("Transfer() error = %v", err)
+			}
+
+			pks, err := transferPackets(t, s, client, func() error {
+				if err := handleServerPacket(s, s.Server(), &packet.Transfer{Address: tt.host, Port: 19133}); err != nil {
+					return err
+				}
+				// Intercepted transfers are scheduled, the packets are read once the session has moved on.
+				waitFor(t, func() bool {
+					s.serverMu.RLock()
+					defer s.serverMu.RUnlock()
+					return s.serverAddr == tt.wantAddr
+				})
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("handleServerPacket() error = %v", err)
+			}
+
+			if forwarded := len(packetsOf[*packet.Transfer](pks)) > 0; forwarded == tt.intercept {
+				t.Fatalf("Transfer packet forwarded = %v, want %v", forwarded, !tt.intercept)
+			}
+			s.serverMu.RLock()
+			addr := s.serverAddr
+			s.serverMu.RUnlock()
+			if addr != tt.wantAddr {
+				t.Fatalf("session connected to %q, want %q", addr, tt.wantAddr)
+			}
+		})
+	}
+}
+
+func TestHandleServerMalformedPackets(t *testing.T) {
+	tests := []struct {
+		name       string
+		malformed  int
+		maxErrors  int
+		wantClosed bool
+	}{
+		{name: "none", maxErrors: 2},
+		{name: "below threshold", malformed: 2, maxErrors: 2},
+		{name: "above threshold", malformed: 3, maxErrors: 2, wantClosed: true},
+		{name: "no tolerance", malformed: 1, wantClosed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var frames [][]byte
+			for range tt.malformed {
+				// The frame holds an unknown decode marker.
+				frames = append(frames, []byte{0xff, 0x01})
+			}
+			frames = append(frames, testFrame(&packet.Text{TextType: packet.TextTypeRaw, Message: "after"}))
+
+			opts := *util.DefaultOpts()
+			opts.FallbackAttempts = -1
+			opts.MaxBackendPacketErrors = tt.maxErrors
+			s, client := newTestSession(t, opts, testTransport{serve: testBackend(testServerGameData, nil, frames...)})
+			if err := s.Transfer("server"); err != nil {
+				t.Fatalf("Transfer() error = %v", err)
+			}
+
+			if tt.wantClosed {
+				select {
+				case <-s.Context().Done():
+				case <-time.After(time.Second * 10):
+					t.Fatal("session survived malformed packets above the threshold")
+				}
+				return
+			}
+			readUntil(t, client.remote(t), func(pk packet.Packet) bool {
+				text, ok := pk.(*packet.Text)
+				return ok && text.Message == "after"
+			})
+			if s.Context().Err() != nil {
+				t.Fatalf("session closed: %v", context.Cause(s.Context()))
+			}
+		})
+	}
+}
+
+// floodingBackend returns a function serving the connection sequence of the game data to a session, followed by
+// chat messages written until the connection is closed.
+func floodingBackend(gameData minecraft.GameData, message string) func(conn net.Conn) {
+	return func(conn net.Conn) {
+		defer conn.Close()
+		reader, writer := protocol.NewReader(conn), protocol.NewWriter(conn)
+		if _, err := reader.ReadPacket(); err != nil {
+			return
+		}
+
+		go func() {
+			for {
+				if _, err := reader.ReadPacket(); err != nil {
+					return
+				}
+			}
+		}()
+		for _, frame := range testSequence(gameData) {
+			if err := writer.Write(frame); err != nil {
+				return
+			}
+		}
+
+		text := testFrame(&packet.Text{TextType: packet.TextTypeRaw, Message: message})
+		for {
+			if err := writer.Write(text); err != nil {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+}
+
+func TestHandleServerSwitchDiscardsOldPackets(t *testing.T) {
+	opts := *util.DefaultOpts()
+	opts.TransferCooldown = 0
+	s, client := newTestSession(t, opts, testTransport{serve: floodingBackend(testServerGameData, "old")})
+	if _, err := transferPackets(t, s, client, func() error { return s.Transfer("old") }); err != nil {
+		t.Fatalf("Transfer() error = %v", err)
+	}
+
+	s.SetTransport(testTransport{serve: testBackend(testServerGameData, nil)})
+	pks, err := transferPackets(t, s, client, func() error { return s.Transfer("new") })
+	if err != nil {
+		t.Fatalf("Transfer() error = %v", err)
+	}
+
+	spawn := slices.IndexFunc(pks, func(pk packet.Packet) bool {
+		_, ok := pk.(*packet.MovePlayer)
+		return ok
+	})
+	if spawn == -1 {
+		t.Fatal("client was not moved to the new server")
+	}
+	for _, text := range packetsOf[*packet.Text](pks[spawn:]) {
+		if text.Message == "old" {
+			t.Fatal("client received a packet of the previous server after the transfer")
+		}
+	}
+}
+
+func TestHandleServerDisconnect(t *testing.T) {
+	disconnect := testFrame(&packet.Disconnect{Message: "Server restarting"})
+	tests := []struct {
+		name         string
+		relay        bool
+		frames       [][]byte
+		fallback     bool
+		wantMessage  string
+		wantFallback bool
+	}{
+		{name: "relayed", relay: true, frames: [][]byte{disconnect}, wantMessage: "Server restarting"},
+		{name: "fallback", frames: [][]byte{disconnect, nil}, fallback: true, wantFallback: true},
+		{name: "fallback disabled", frames: [][]byte{disconnect, nil}, wantMessage: "fallback failed"},
+		{name: "connection lost", frames: [][]byte{nil}, wantMessage: "fallback failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := *util.DefaultOpts()
+			opts.RelayServerDisconnect = tt.relay
+			opts.FallbackAttempts = -1
+			if tt.fallback {
+				opts.FallbackAttempts = 0
+			}
+			opts.TransferCooldown = 0
+			s, client := newTestSession(t, opts, testTransport{serve: testBackend(testServerGameData, nil, tt.frames...)})
+			s.discovery = testDiscovery{addr: "lobby"}
+			if err := s.Transfer("server"); err != nil {
+				t.Fatalf("Transfer() error = %v", err)
+			}
+
+			if tt.wantFallback {
+				waitFor(t, func() bool {
+					s.serverMu.RLock()
+					defer s.serverMu.RUnlock()
+					return s.serverAddr == "lobby"
+				})
+				if err := s.waitTransfer(); err != nil {
+					t.Fatalf("session closed: %v", err)
+				}
+				return
+			}
+
+			// The client closes its connection with the message of the Disconnect packet it received.
+			remote := client.remote(t)
+			_ = remote.SetReadDeadline(time.Now().Add(time.Second * 10))
+			var err error
+			for err == nil {
+				_, err = remote.ReadPacket()
+			}
+			if !strings.Contains(err.Error(), tt.wantMessage) {
+				t.Fatalf("client disconnected with %q, want %q", err, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestHandleUnknownClientPacket(t *testing.T) {
+	tests := []struct {
+		name          string
+		policy        string
+		wantErr       bool
+		wantForwarded bool
+		wantClosed    bool
+	}{
+		{name: "default", wantErr: true},
+		{name: "forward raw", policy: util.UnknownClientPacketPolicyForwardRaw, wantForwarded: true},
+		{name: "drop", policy: util.UnknownClientPacketPolicyDrop},
+		{name: "disconnect", policy: util.UnknownClientPacketPolicyDisconnect, wantClosed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := *util.DefaultOpts()
+			opts.UnknownClientPacketPolicy = tt.policy
+			s, _ := newTestSession(t, opts, testTransport{serve: testBackend(testServerGameData, nil)})
+			if err := s.Transfer("server"); err != nil {
+				t.Fatalf("Transfer() error = %v", err)
+			}
+			if err := s.waitTransfer(); err != nil {
+				t.Fatalf("session closed: %v", err)
+			}
+
+			payload := []byte{0xfe, 0x07, 0x01, 0x02}
+			var forwarded bool
+			conn := s.Server()
+			conn.SetRawTap(func(direction int, frame []byte) {
+				if decoded, err := snappy.Decode(nil, frame); err == nil && bytes.Equal(decoded, payload) {
+					forwarded = true
+				}
+			})
+			if err := handleUnknownClientPacket(s, conn, 1022, payload); (err != nil) != tt.wantErr {
+				t.Fatalf("handleUnknownClientPacket() error = %v, want error %v", err, tt.wantErr)
+			}
+			if forwarded != tt.wantForwarded {
+				t.Fatalf("packet forwarded = %v, want %v", forwarded, tt.wantForwarded)
+			}
+			if closed := s.Context().Err() != nil; closed != tt.wantClosed {
+				t.Fatalf("session closed = %v, want %v", closed, tt.wantClosed)
+			}
+		})
+	}
+}
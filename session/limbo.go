@@ -0,0 +1,140 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/cooldogedev/spectrum/server"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/sandertv/gophertunnel/minecraft"
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+)
+
+// minLimboRetryInterval is the minimum interval in milliseconds at which discovery is retried for sessions held
+// in limbo, which util.Opts.LimboRetryInterval is raised to if it is lower.
+const minLimboRetryInterval = 1000
+
+// limboGameData is the game data of the empty world served by the proxy to sessions held in limbo.
+var limboGameData = minecraft.GameData{
+	WorldName:         "Limbo",
+	EntityUniqueID:    1,
+	EntityRuntimeID:   1,
+	PlayerGameMode:    packet.GameTypeSpectator,
+	BaseGameVersion:   "*",
+	PlayerPosition:    mgl32.Vec3{0.5, 64, 0.5},
+	Dimension:         packet.DimensionOverworld,
+	WorldSpawn:        protocol.BlockPos{0, 64, 0},
+	WorldGameMode:     packet.GameTypeSpectator,
+	PlayerPermissions: packet.PermissionLevelVisitor,
+}
+
+// enterLimbo spawns the client in an empty world served by the proxy rather than a server, holding it there
+// until a server is discovered by handleLimbo.
+func (s *Session) enterLimbo(ctx context.Context) error {
+	s.inLimbo.Store(true)
+	s.wg.Add(3)
+	go handleServer(s)
+	go handleClient(s)
+	go handleLatency(s)
+	if err := s.client.StartGameContext(ctx, limboGameData); err != nil {
+		s.logger.Debug("startgame sequence failed", "err", err)
+		return err
+	}
+
+	s.sendChunks(limboGameData.Dimension, limboGameData.PlayerPosition)
+	s.sendLimboNotice()
+	_ = s.Flush()
+
+	s.wg.Add(1)
+	go handleLimbo(s)
+	if !s.registry.register(s.client.IdentityData().XUID, s) {
+		return errors.New("logged in from another location")
+	}
+	s.logger.Info("logged in session to limbo")
+	return nil
+}
+
+// holdInLimbo holds the session in limbo once its server connection was lost and no fallback server could be
+// transferred to, resetting the player to an empty world while handleLimbo keeps discovering a server.
+func (s *Session) holdInLimbo(conn *server.Conn) {
+	s.clearServer(conn)
+	if !s.inLimbo.CompareAndSwap(false, true) {
+		return
+	}
+	s.inFallback.Store(false)
+
+	// The player remains in the dimension of the lost server, as changing dimensions requires the animation.
+	gameData := conn.GameData()
+	gameData.PlayerPosition = limboGameData.PlayerPosition
+	gameData.PlayerGameMode = limboGameData.PlayerGameMode
+	s.sendGameData(gameData, transferRequest{})
+	s.sendLimboNotice()
+	_ = s.Flush()
+
+	s.wg.Add(1)
+	go handleLimbo(s)
+	s.logger.Info("holding session in limbo")
+}
+
+// leaveLimbo marks the session as no longer held in limbo once it was transferred to a server.
+func (s *Session) leaveLimbo() {
+	if !s.inLimbo.Swap(false) {
+		return
+	}
+
+	if s.opts.Load().LimboTitle != "" {
+		_ = s.client.WritePacket(&packet.SetTitle{ActionType: packet.TitleActionClear})
+		_ = s.client.WritePacket(&packet.SetTitle{ActionType: packet.TitleActionReset})
+	}
+}
+
+// sendLimboNotice sends the configured message and title to a player held in limbo.
+func (s *Session) sendLimboNotice() {
+	opts := s.opts.Load()
+	if opts.LimboMessage != "" {
+		s.sendMessage(opts.LimboMessage)
+	}
+
+	if opts.LimboTitle != "" {
+		_ = s.client.WritePacket(&packet.SetTitle{ActionType: packet.TitleActionSetDurations, RemainDuration: math.MaxInt32})
+		_ = s.client.WritePacket(&packet.SetTitle{ActionType: packet.TitleActionSetTitle, Text: opts.LimboTitle})
+	}
+}
+
+// InLimbo reports whether the session is held in limbo, waiting for a server to become available.
+func (s *Session) InLimbo() bool {
+	return s.inLimbo.Load()
+}
+
+// handleLimbo periodically runs the session's discovery while it is held in limbo, transferring it to the
+// discovered server once one is available.
+func handleLimbo(s *Session) {
+	defer s.wg.Done()
+	ticker := time.NewTicker(time.Millisecond * time.Duration(max(s.opts.Load().LimboRetryInterval, minLimboRetryInterval)))
+	defer ticker.Stop()
+	for s.inLimbo.Load() {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if !s.inLimbo.Load() || s.TransferState().InProgress() {
+			continue
+		}
+
+		result, err := s.discover(s.discovery, false)
+		if err != nil {
+			s.logger.Debug("limbo discovery failed", "err", err)
+			continue
+		}
+
+		if err := s.forceTransfer(result.Target(), result.Group, nil); err != nil {
+			logError(s, "failed to transfer session out of limbo", fmt.Errorf("transfer to %v failed: %w", result.Target(), err))
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package session
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/cooldogedev/spectrum/util"
+	"github.com/sandertv/gophertunnel/minecraft"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+)
+
+// failingDiscovery is a discovery failing its first discoveries, until failures reaches 0, and returning the
+// same server afterwards.
+type failingDiscovery struct {
+	addr     string
+	failures *atomic.Int32
+}
+
+// Discover ...
+func (d failingDiscovery) Discover(*minecraft.Conn) (string, error) {
+	if d.failures.Add(-1) >= 0 {
+		return "", errors.New("no server available")
+	}
+	return d.addr, nil
+}
+
+// DiscoverFallback ...
+func (d failingDiscovery) DiscoverFallback(conn *minecraft.Conn) (string, error) {
+	return d.Discover(conn)
+}
+
+func TestSessionLimbo(t *testing.T) {
+	tests := []struct {
+		name      string
+		limbo     bool
+		failures  int32
+		wantErr   bool
+		wantLimbo bool
+	}{
+		{name: "server available", limbo: true},
+		{name: "held in limbo", limbo: true, failures: 2, wantLimbo: true},
+		{name: "limbo disabled", failures: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := *util.DefaultOpts()
+			opts.LimboOnNoBackend = tt.limbo
+			opts.LimboMessage = "waiting for a server"
+			opts.LimboRetryInterval = minLimboRetryInterval
+			failures := &atomic.Int32{}
+			failures.Store(tt.failures)
+			discovery := failingDiscovery{addr: "server", failures: failures}
+			s, client := newTestLoginSession(t, opts, NewRegistry(), discovery, testTransport{serve: testBackend(testServerGameData, nil)})
+			if err := s.Login(); (err != nil) != tt.wantErr {
+				t.Fatalf("Login() error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if s.InLimbo() != tt.wantLimbo {
+				t.Fatalf("InLimbo() = %v, want %v", s.InLimbo(), tt.wantLimbo)
+			}
+			if tt.wantLimbo {
+				readUntil(t, client.remote(t), func(pk packet.Packet) bool {
+					text, ok := pk.(*packet.Text)
+					return ok && text.Message == opts.LimboMessage
+				})
+			}
+
+			waitFor(t, func() bool { return !s.InLimbo() && s.Server() != nil })
+			s.serverMu.RLock()
+			addr := s.serverAddr
+			s.serverMu.RUnlock()
+			if addr != "server" {
+				t.Fatalf("session connected to %q, want %q", addr, "server")
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package session
+
+import (
+	"context"
+	"errors"
+
+	"github.com/cooldogedev/spectrum/util"
+)
+
+// messageError is an error associated with a message of util.Messages, which the player is disconnected with
+// if the session is closed because of it.
+type messageError struct {
+	key  string
+	addr string
+	err  error
+}
+
+// Error ...
+func (e *messageError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap ...
+func (e *messageError) Unwrap() error {
+	return e.err
+}
+
+// withMessage associates the error with the message of the key, unless it is already associated with one.
+func withMessage(key string, addr string, err error) error {
+	var e *messageError
+	if errors.As(err, &e) {
+		return err
+	}
+	return &messageError{key: key, addr: addr, err: err}
+}
+
+// disconnectMessage returns the message the player is disconnected with when the session is closed with the
+// error, formatted according to the player's language if the error is associated with a message.
+func (s *Session) disconnectMessage(err error) string {
+	var e *messageError
+	if !errors.As(err, &e) {
+		return err.Error()
+	}
+
+	messages := s.opts.Load().Messages
+	message, ok := messages.Format(s.client.ClientData().LanguageCode, e.key, e.addr, e.err.Error())
+	if !ok {
+		return err.Error()
+	}
+	return message
+}
+
+// loginError associates an error of the login stage with the message of the key, such as util.MessageDialFailed.
+// Errors of logins that ran out of time are associated with util.MessageLoginTimeout instead, regardless of the
+// stage they failed at.
+func loginError(ctx context.Context, key string, addr string, err error) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return withMessage(util.MessageLoginTimeout, addr, err)
+	}
+	return withMessage(key, addr, err)
+}
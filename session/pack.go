@@ -0,0 +1,43 @@
+package session
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ResourcePackMismatchError is returned when a transfer is refused because the target server requires resource
+// packs that were not sent to the client at login, as reported by util.Opts.ServerResourcePacks. The client
+// only downloads resource packs while connecting to the proxy, so the player has to reconnect to obtain them.
+type ResourcePackMismatchError struct {
+	// Addr is the address of the server the transfer was refused to.
+	Addr string
+	// Missing holds the UUIDs of the resource packs required by the server that the client does not have.
+	Missing []string
+}
+
+// Error ...
+func (e *ResourcePackMismatchError) Error() string {
+	return fmt.Sprintf("server %v requires resource packs missing from the client: %v", e.Addr, strings.Join(e.Missing, ", "))
+}
+
+// missingResourcePacks returns the UUIDs of the resource packs required by the server at the provided address
+// that were not sent to the client at login.
+func (s *Session) missingResourcePacks(addr string) []string {
+	fn := s.opts.Load().ServerResourcePacks
+	if fn == nil {
+		return nil
+	}
+
+	packs := make(map[string]struct{}, len(s.client.ResourcePacks()))
+	for _, pack := range s.client.ResourcePacks() {
+		packs[strings.ToLower(pack.UUID().String())] = struct{}{}
+	}
+
+	var missing []string
+	for _, id := range fn(addr) {
+		if _, ok := packs[strings.ToLower(id)]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	return missing
+}
@@ -1,6 +1,8 @@
 package session
 
 import (
+	"slices"
+
 	"github.com/sandertv/gophertunnel/minecraft"
 	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
 )
@@ -8,6 +10,7 @@ import (
 // Context represents the context of an action. It holds the state of whether the action has been canceled.
 type Context struct {
 	canceled bool
+	message  string
 }
 
 // NewContext returns a new context.
@@ -20,12 +23,26 @@ func (c *Context) Cancel() {
 	c.canceled = true
 }
 
+// CancelWithMessage marks the context as canceled and attaches a message describing the reason. Actions
+// that support it, such as transfers, display the message to the player.
+func (c *Context) CancelWithMessage(message string) {
+	c.canceled = true
+	c.message = message
+}
+
 // Cancelled returns whether the context has been cancelled.
 func (c *Context) Cancelled() bool {
 	return c.canceled
 }
 
-// Processor defines methods for processing various actions within a proxy session.
+// Message returns the message attached using CancelWithMessage, or an empty string if none was attached.
+func (c *Context) Message() string {
+	return c.message
+}
+
+// Processor defines methods for processing various actions within a proxy session. Packets are passed by
+// pointer, so that processors may modify them or replace them entirely, and cancelling the Context drops the
+// packet instead of forwarding it.
 type Processor interface {
 	// ProcessStartGame is called only once during the login sequence.
 	ProcessStartGame(ctx *Context, data *minecraft.GameData)
@@ -39,11 +56,16 @@ type Processor interface {
 	ProcessClientEncoded(ctx *Context, pk *[]byte)
 	// ProcessFlush is called before flushing the player's minecraft.Conn buffer in response to a downstream server request.
 	ProcessFlush(ctx *Context)
-	// ProcessPreTransfer is called before transferring the player to a different server.
+	// ProcessPreTransfer is called before transferring the player to a different server. The transfer may be
+	// rejected using Context.CancelWithMessage, in which case the message is sent to the player.
 	ProcessPreTransfer(ctx *Context, origin *string, target *string)
-	// ProcessTransferFailure is called when the player transfer to a different server fails.
-	ProcessTransferFailure(ctx *Context, origin *string, target *string)
+	// ProcessTransferFailure is called with the error the transfer failed with when the player transfer to a
+	// different server fails, including when it is refused after ProcessPreTransfer was called, allowing
+	// processors to roll back the state they prepared for it.
+	// Transfers started from this method must use Session.ScheduleTransfer rather than Session.Transfer.
+	ProcessTransferFailure(ctx *Context, origin *string, target *string, err error)
 	// ProcessPostTransfer is called after transferring the player to a different server.
+	// Transfers started from this method must use Session.ScheduleTransfer rather than Session.Transfer.
 	ProcessPostTransfer(ctx *Context, origin *string, target *string)
 	// ProcessCache is called before updating the session's cache.
 	ProcessCache(ctx *Context, new *[]byte)
@@ -57,14 +79,213 @@ type NopProcessor struct{}
 // Ensure that NopProcessor satisfies the Processor interface.
 var _ Processor = NopProcessor{}
 
-func (NopProcessor) ProcessStartGame(_ *Context, _ *minecraft.GameData)      {}
-func (NopProcessor) ProcessServer(_ *Context, _ *packet.Packet)              {}
-func (NopProcessor) ProcessServerEncoded(_ *Context, _ *[]byte)              {}
-func (NopProcessor) ProcessClient(_ *Context, _ *packet.Packet)              {}
-func (NopProcessor) ProcessClientEncoded(_ *Context, _ *[]byte)              {}
-func (NopProcessor) ProcessFlush(_ *Context)                                 {}
-func (NopProcessor) ProcessPreTransfer(_ *Context, _ *string, _ *string)     {}
-func (NopProcessor) ProcessTransferFailure(_ *Context, _ *string, _ *string) {}
-func (NopProcessor) ProcessPostTransfer(_ *Context, _ *string, _ *string)    {}
-func (NopProcessor) ProcessCache(_ *Context, _ *[]byte)                      {}
-func (NopProcessor) ProcessDisconnection(_ *Context, _ *string)              {}
+func (NopProcessor) ProcessStartGame(_ *Context, _ *minecraft.GameData)               {}
+func (NopProcessor) ProcessServer(_ *Context, _ *packet.Packet)                       {}
+func (NopProcessor) ProcessServerEncoded(_ *Context, _ *[]byte)                       {}
+func (NopProcessor) ProcessClient(_ *Context, _ *packet.Packet)                       {}
+func (NopProcessor) ProcessClientEncoded(_ *Context, _ *[]byte)                       {}
+func (NopProcessor) ProcessFlush(_ *Context)                                          {}
+func (NopProcessor) ProcessPreTransfer(_ *Context, _ *string, _ *string)              {}
+func (NopProcessor) ProcessTransferFailure(_ *Context, _ *string, _ *string, _ error) {}
+func (NopProcessor) ProcessPostTransfer(_ *Context, _ *string, _ *string)             {}
+func (NopProcessor) ProcessCache(_ *Context, _ *[]byte)                               {}
+func (NopProcessor) ProcessDisconnection(_ *Context, _ *string)                       {}
+
+// Subscriber may be implemented by a Processor to only process the packets it is interested in. Packets with
+// other IDs are not passed to its ProcessServer, ProcessServerEncoded, ProcessClient and ProcessClientEncoded
+// methods, which saves dispatching every packet to processors that ignore most of them.
+type Subscriber interface {
+	// Subscribed returns the IDs of the packets processed by the processor, or nil if it processes all packets.
+	// It is called once when the processor is set or added to a session.
+	Subscribed() []uint32
+}
+
+// processorEntry is a processor added to a session using Session.AddProcessor.
+type processorEntry struct {
+	processor Processor
+	priority  int
+	ids       map[uint32]struct{}
+}
+
+// newProcessorEntry creates a processorEntry for the processor, resolving its subscriptions if it implements
+// Subscriber.
+func newProcessorEntry(processor Processor, priority int) *processorEntry {
+	entry := &processorEntry{processor: processor, priority: priority}
+	subscriber, ok := processor.(Subscriber)
+	if !ok {
+		return entry
+	}
+
+	if ids := subscriber.Subscribed(); ids != nil {
+		entry.ids = make(map[uint32]struct{}, len(ids))
+		for _, id := range ids {
+			entry.ids[id] = struct{}{}
+		}
+	}
+	return entry
+}
+
+// processorChain is a Processor executing multiple processors in order. Actions that can be cancelled stop
+// being passed on once a processor cancels the context, while the other actions reach every processor.
+type processorChain []chainedProcessor
+
+// chainedProcessor is a processor of a processorChain.
+type chainedProcessor struct {
+	Processor
+	ids map[uint32]struct{}
+}
+
+// subscribed reports whether the processor processes packets with the provided ID.
+func (p chainedProcessor) subscribed(id uint32) bool {
+	if p.ids == nil {
+		return true
+	}
+	_, ok := p.ids[id]
+	return ok
+}
+
+// newProcessor returns a Processor executing the processors of the entries in order. A NopProcessor is
+// returned if there are no entries, and the processor itself if there is a single entry without subscriptions.
+func newProcessor(entries []*processorEntry) Processor {
+	if len(entries) == 0 {
+		return NopProcessor{}
+	}
+
+	if len(entries) == 1 && entries[0].ids == nil {
+		return entries[0].processor
+	}
+
+	chain := make(processorChain, len(entries))
+	for i, entry := range entries {
+		chain[i] = chainedProcessor{Processor: entry.processor, ids: entry.ids}
+	}
+	return chain
+}
+
+// processes reports whether the processor processes packets with the provided ID. A NopProcessor processes no
+// packets, while processors that do not implement Subscriber process all of them.
+func processes(processor Processor, id uint32) bool {
+	switch processor := processor.(type) {
+	case NopProcessor:
+		return false
+	case processorChain:
+		return slices.ContainsFunc(processor, func(p chainedProcessor) bool {
+			return p.subscribed(id)
+		})
+	default:
+		return true
+	}
+}
+
+// encodedPacketID returns the ID of the encoded packet, read from its packet.Header.
+func encodedPacketID(payload []byte) (uint32, bool) {
+	var value uint32
+	for i := 0; i < len(payload) && i < 5; i++ {
+		value |= uint32(payload[i]&0x7f) << (7 * i)
+		if payload[i]&0x80 == 0 {
+			return value & 0x3ff, true
+		}
+	}
+	return 0, false
+}
+
+// Ensure that processorChain satisfies the Processor interface.
+var _ Processor = processorChain{}
+
+func (chain processorChain) ProcessStartGame(ctx *Context, data *minecraft.GameData) {
+	for _, p := range chain {
+		if p.ProcessStartGame(ctx, data); ctx.Cancelled() {
+			return
+		}
+	}
+}
+
+func (chain processorChain) ProcessServer(ctx *Context, pk *packet.Packet) {
+	id := (*pk).ID()
+	for _, p := range chain {
+		if !p.subscribed(id) {
+			continue
+		}
+		if p.ProcessServer(ctx, pk); ctx.Cancelled() {
+			return
+		}
+	}
+}
+
+func (chain processorChain) ProcessServerEncoded(ctx *Context, pk *[]byte) {
+	id, ok := encodedPacketID(*pk)
+	for _, p := range chain {
+		if ok && !p.subscribed(id) {
+			continue
+		}
+		if p.ProcessServerEncoded(ctx, pk); ctx.Cancelled() {
+			return
+		}
+	}
+}
+
+func (chain processorChain) ProcessClient(ctx *Context, pk *packet.Packet) {
+	id := (*pk).ID()
+	for _, p := range chain {
+		if !p.subscribed(id) {
+			continue
+		}
+		if p.ProcessClient(ctx, pk); ctx.Cancelled() {
+			return
+		}
+	}
+}
+
+func (chain processorChain) ProcessClientEncoded(ctx *Context, pk *[]byte) {
+	id, ok := encodedPacketID(*pk)
+	for _, p := range chain {
+		if ok && !p.subscribed(id) {
+			continue
+		}
+		if p.ProcessClientEncoded(ctx, pk); ctx.Cancelled() {
+			return
+		}
+	}
+}
+
+func (chain processorChain) ProcessFlush(ctx *Context) {
+	for _, p := range chain {
+		if p.ProcessFlush(ctx); ctx.Cancelled() {
+			return
+		}
+	}
+}
+
+func (chain processorChain) ProcessPreTransfer(ctx *Context, origin *string, target *string) {
+	for _, p := range chain {
+		if p.ProcessPreTransfer(ctx, origin, target); ctx.Cancelled() {
+			return
+		}
+	}
+}
+
+func (chain processorChain) ProcessTransferFailure(ctx *Context, origin *string, target *string, err error) {
+	for _, p := range chain {
+		p.ProcessTransferFailure(ctx, origin, target, err)
+	}
+}
+
+func (chain processorChain) ProcessPostTransfer(ctx *Context, origin *string, target *string) {
+	for _, p := range chain {
+		p.ProcessPostTransfer(ctx, origin, target)
+	}
+}
+
+func (chain processorChain) ProcessCache(ctx *Context, new *[]byte) {
+	for _, p := range chain {
+		if p.ProcessCache(ctx, new); ctx.Cancelled() {
+			return
+		}
+	}
+}
+
+func (chain processorChain) ProcessDisconnection(ctx *Context, message *string) {
+	for _, p := range chain {
+		p.ProcessDisconnection(ctx, message)
+	}
+}
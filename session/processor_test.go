@@ -0,0 +1,43 @@
+package session
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"github.com/cooldogedev/spectrum/util"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+)
+
+// countingProcessor is a processor counting the packets sent by the server.
+type countingProcessor struct {
+	NopProcessor
+	count *atomic.Int32
+}
+
+// ProcessServer ...
+func (p countingProcessor) ProcessServer(*Context, *packet.Packet) {
+	p.count.Add(1)
+}
+
+func TestSessionSetProcessorConcurrent(t *testing.T) {
+	s, _ := newTestSession(t, *util.DefaultOpts(), testTransport{serve: floodingBackend(testServerGameData, "flood")})
+	if err := s.Transfer("server"); err != nil {
+		t.Fatalf("Transfer() error = %v", err)
+	}
+	waitFor(t, func() bool { return s.TransferState() == TransferStateCompleted })
+
+	count := &atomic.Int32{}
+	for i := range 200 {
+		switch i % 3 {
+		case 0:
+			s.SetProcessor(countingProcessor{count: count})
+		case 1:
+			remove := s.AddProcessor(countingProcessor{count: count}, i)
+			defer remove()
+		case 2:
+			s.SetProcessor(nil)
+		}
+	}
+	s.SetProcessor(countingProcessor{count: count})
+	waitFor(t, func() bool { return count.Load() > 0 })
+}
@@ -0,0 +1,100 @@
+package session
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// transferQueue serializes the transfers queued using Session.QueueTransfer.
+type transferQueue struct {
+	pending []*queuedTransfer
+	running bool
+	mu      sync.Mutex
+}
+
+// queuedTransfer is a transfer waiting in a transferQueue, along with the callbacks of every request it coalesces.
+type queuedTransfer struct {
+	addr      string
+	callbacks []func(err error)
+}
+
+// QueueTransfer queues a transfer to the specified address, performed on a separate goroutine once the
+// transfers queued before it and any transfer in progress have finished. Queuing a transfer to an address
+// that is already waiting in the queue coalesces both requests into a single transfer. The callback, which may
+// be nil, is called with the result of the transfer once it has completed or failed, or with the cause of the
+// session's closure if it closes first.
+func (s *Session) QueueTransfer(addr string, callback func(err error)) {
+	q := &s.transferQueue
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, transfer := range q.pending {
+		if transfer.addr == addr {
+			transfer.callbacks = appendCallback(transfer.callbacks, callback)
+			return
+		}
+	}
+
+	q.pending = append(q.pending, &queuedTransfer{addr: addr, callbacks: appendCallback(nil, callback)})
+	if !q.running {
+		q.running = true
+		s.wg.Add(1)
+		go s.drainTransferQueue()
+	}
+}
+
+// drainTransferQueue performs the queued transfers in order until the queue is empty.
+func (s *Session) drainTransferQueue() {
+	defer s.wg.Done()
+	q := &s.transferQueue
+	for {
+		q.mu.Lock()
+		if len(q.pending) == 0 {
+			q.running = false
+			q.mu.Unlock()
+			return
+		}
+		transfer := q.pending[0]
+		q.pending = q.pending[1:]
+		q.mu.Unlock()
+
+		err := s.waitTransfer()
+		if err == nil {
+			// The transfer only finishes once OnComplete is called, which may be after TransferWithOptions returned.
+			done := make(chan error, 1)
+			options := DefaultTransferOptions()
+			options.OnComplete = func(err error) {
+				done <- err
+			}
+			_ = s.TransferWithOptions(transfer.addr, options)
+			err = <-done
+		}
+
+		for _, callback := range transfer.callbacks {
+			callback(err)
+		}
+	}
+}
+
+// waitTransfer blocks until no transfer is in progress, returning the cause of the session's closure if it
+// closes first.
+func (s *Session) waitTransfer() error {
+	ticker := time.NewTicker(time.Millisecond * 50)
+	defer ticker.Stop()
+	for s.TransferState().InProgress() {
+		select {
+		case <-s.ctx.Done():
+			return context.Cause(s.ctx)
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// appendCallback appends the callback to the callbacks if it is not nil.
+func appendCallback(callbacks []func(err error), callback func(err error)) []func(err error) {
+	if callback == nil {
+		return callbacks
+	}
+	return append(callbacks, callback)
+}
@@ -3,23 +3,143 @@ package session
 import (
 	"strings"
 	"sync"
+
+	"github.com/google/uuid"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
 )
 
 type Registry struct {
 	sessions map[string]*Session
+	names    map[string]*Session
+	uuids    map[uuid.UUID]*Session
+	servers  map[string]map[*Session]struct{}
+	addrs    map[*Session]string
+	pending  map[string]*Session
 	mu       sync.RWMutex
+
+	subscribers hooks[Event]
 }
 
 func NewRegistry() *Registry {
 	return &Registry{
 		sessions: make(map[string]*Session),
+		names:    make(map[string]*Session),
+		uuids:    make(map[uuid.UUID]*Session),
+		servers:  make(map[string]map[*Session]struct{}),
+		addrs:    make(map[*Session]string),
+		pending:  make(map[string]*Session),
 	}
 }
 
+// Subscribe subscribes the function to the lifecycle events of the registry's sessions, returning a function
+// that cancels the subscription. The function is called synchronously on the goroutine the event occurred on,
+// so it should not block, and it must use a type switch to handle specific events.
+func (r *Registry) Subscribe(fn func(event Event)) (unsubscribe func()) {
+	return r.subscribers.add(fn)
+}
+
+// OnAdd subscribes the function to the sessions added to the registry once they logged in, returning a function
+// that cancels the subscription. It is called for the same sessions as EventLogin, synchronously once the session
+// has been added, so it should not block.
+func (r *Registry) OnAdd(fn func(session *Session)) (unsubscribe func()) {
+	return r.Subscribe(func(event Event) {
+		if event, ok := event.(EventLogin); ok {
+			fn(event.Session())
+		}
+	})
+}
+
+// OnRemove subscribes the function to the sessions removed from the registry once they are closed, returning a
+// function that cancels the subscription. It is called for the same sessions as EventDisconnect, which is only
+// dispatched for sessions that were added to the registry, synchronously once the session has been removed, so
+// it should not block.
+func (r *Registry) OnRemove(fn func(session *Session)) (unsubscribe func()) {
+	return r.Subscribe(func(event Event) {
+		if event, ok := event.(EventDisconnect); ok {
+			fn(event.Session())
+		}
+	})
+}
+
+// publish dispatches the event to the functions subscribed to the registry.
+func (r *Registry) publish(event Event) {
+	r.subscribers.call(event)
+}
+
 func (r *Registry) AddSession(xuid string, session *Session) {
+	r.add(xuid, session, false)
+}
+
+// reserve reserves the XUID for the session while it logs in, so that concurrent logins of the same player are
+// detected before either of them is added to the registry. The other sessions of the player that are registered
+// or logging in are returned. If rejectNew is true, the XUID is only reserved if there are none, and
+// errAlreadyLoggedIn is returned otherwise. If limit is above 0, ErrProxyFull is returned if as many sessions of
+// other players are registered or logging in, so that concurrent logins cannot exceed it.
+func (r *Registry) reserve(xuid string, session *Session, rejectNew bool, limit int) (existing []*Session, err error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	count := len(r.sessions) + len(r.pending)
+	if registered, ok := r.sessions[xuid]; ok {
+		count--
+		if registered != session {
+			existing = append(existing, registered)
+		}
+	}
+	if pending, ok := r.pending[xuid]; ok {
+		count--
+		if pending != session {
+			existing = append(existing, pending)
+		}
+	}
+	if rejectNew && len(existing) > 0 {
+		return existing, errAlreadyLoggedIn
+	}
+
+	if limit > 0 && count >= limit {
+		return existing, ErrProxyFull
+	}
+	r.pending[xuid] = session
+	return existing, nil
+}
+
+// release releases the XUID reserved by the session, if it is still reserved for it.
+func (r *Registry) release(xuid string, session *Session) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.pending[xuid] == session {
+		delete(r.pending, xuid)
+	}
+}
+
+// register adds the session to the registry if the XUID is still reserved for it, reporting whether it was
+// added. The reservation is lost once a newer login of the player took it over or the session was closed.
+func (r *Registry) register(xuid string, session *Session) bool {
+	return r.add(xuid, session, true)
+}
+
+// add adds the session to the registry, replacing the session previously registered with the XUID. If reserved
+// is true, the session is only added if the XUID is reserved for it. EventLogin is dispatched the first time the
+// session is added.
+func (r *Registry) add(xuid string, session *Session, reserved bool) bool {
+	r.mu.Lock()
+	if reserved && r.pending[xuid] != session {
+		r.mu.Unlock()
+		return false
+	}
+
+	if previous, ok := r.sessions[xuid]; ok {
+		r.unindex(previous)
+	}
 	r.sessions[xuid] = session
+	r.index(session)
+	if r.pending[xuid] == session {
+		delete(r.pending, xuid)
+	}
+	r.mu.Unlock()
+	if session.loggedIn.CompareAndSwap(false, true) {
+		r.publish(EventLogin{session: session})
+	}
+	return true
 }
 
 func (r *Registry) GetSession(xuid string) *Session {
@@ -28,22 +148,153 @@ func (r *Registry) GetSession(xuid string) *Session {
 	return r.sessions[xuid]
 }
 
+// GetSessionByName returns the session of the player with the provided display name, ignoring case, or nil if
+// it is not online.
+func (r *Registry) GetSessionByName(name string) *Session {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.names[strings.ToLower(name)]
+}
+
+// GetSessionByUsername returns the session of the player with the provided display name, ignoring case.
+//
+// Deprecated: Use GetSessionByName instead.
 func (r *Registry) GetSessionByUsername(username string) *Session {
+	return r.GetSessionByName(username)
+}
+
+// GetSessionByPrefix returns the session of the player whose name starts with the provided prefix, ignoring case.
+// If the names of multiple players start with it, the session of the player with the shortest name is returned.
+func (r *Registry) GetSessionByPrefix(prefix string) *Session {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
+	prefix = strings.ToLower(prefix)
+	if session, ok := r.names[prefix]; ok {
+		return session
+	}
 
-	for _, session := range r.sessions {
-		if strings.EqualFold(session.client.IdentityData().DisplayName, username) {
-			return session
+	var (
+		found  *Session
+		length int
+	)
+	for name, session := range r.names {
+		if strings.HasPrefix(name, prefix) && (found == nil || len(name) < length) {
+			found, length = session, len(name)
 		}
 	}
-	return nil
+	return found
+}
+
+// GetSessionByUUID returns the session of the player with the provided identity UUID, or nil if it is not online.
+func (r *Registry) GetSessionByUUID(id uuid.UUID) *Session {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.uuids[id]
 }
 
 func (r *Registry) RemoveSession(xuid string) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	delete(r.sessions, xuid)
+	if session, ok := r.sessions[xuid]; ok {
+		r.unindex(session)
+		delete(r.sessions, xuid)
+	}
+}
+
+// removeSession removes the session registered with the given XUID if it is the provided session, leaving
+// a newer session of the same player registered.
+func (r *Registry) removeSession(xuid string, session *Session) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.sessions[xuid] == session {
+		r.unindex(session)
+		delete(r.sessions, xuid)
+	}
+}
+
+// SessionsByServer returns the sessions connected to the server with the provided address.
+func (r *Registry) SessionsByServer(addr string) []*Session {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	sessions := make([]*Session, 0, len(r.servers[addr]))
+	for session := range r.servers[addr] {
+		sessions = append(sessions, session)
+	}
+	return sessions
+}
+
+// ServerCounts returns the amount of sessions connected to every server that sessions of the registry are
+// connected to, keyed by the address of the server.
+func (r *Registry) ServerCounts() map[string]int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	counts := make(map[string]int, len(r.servers))
+	for addr, sessions := range r.servers {
+		counts[addr] = len(sessions)
+	}
+	return counts
+}
+
+// updateServer moves the session to the provided server address in the server index of the registry, if the
+// session is registered.
+func (r *Registry) updateServer(session *Session, addr string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.sessions[session.client.IdentityData().XUID] == session {
+		r.unindexServer(session)
+		r.indexServer(session, addr)
+	}
+}
+
+// index adds the session to the name, UUID and server indexes of the registry.
+func (r *Registry) index(session *Session) {
+	identityData := session.client.IdentityData()
+	r.names[strings.ToLower(identityData.DisplayName)] = session
+	if id, err := uuid.Parse(identityData.Identity); err == nil {
+		r.uuids[id] = session
+	}
+
+	session.serverMu.RLock()
+	addr := session.serverAddr
+	session.serverMu.RUnlock()
+	r.indexServer(session, addr)
+}
+
+// unindex removes the session from the name, UUID and server indexes of the registry, if it is indexed.
+func (r *Registry) unindex(session *Session) {
+	identityData := session.client.IdentityData()
+	if name := strings.ToLower(identityData.DisplayName); r.names[name] == session {
+		delete(r.names, name)
+	}
+	if id, err := uuid.Parse(identityData.Identity); err == nil && r.uuids[id] == session {
+		delete(r.uuids, id)
+	}
+	r.unindexServer(session)
+}
+
+func (r *Registry) indexServer(session *Session, addr string) {
+	if addr == "" {
+		return
+	}
+
+	if r.servers[addr] == nil {
+		r.servers[addr] = make(map[*Session]struct{})
+	}
+	r.servers[addr][session] = struct{}{}
+	r.addrs[session] = addr
+}
+
+func (r *Registry) unindexServer(session *Session) {
+	addr, ok := r.addrs[session]
+	if !ok {
+		return
+	}
+
+	delete(r.addrs, session)
+	delete(r.servers[addr], session)
+	if len(r.servers[addr]) == 0 {
+		delete(r.servers, addr)
+	}
 }
 
 func (r *Registry) GetSessions() []*Session {
@@ -56,3 +307,68 @@ func (r *Registry) GetSessions() []*Session {
 	}
 	return sessions
 }
+
+// Range calls the function for every session of the registry until it returns false. The function is called
+// without the registry being locked, so it may add or remove sessions, such as by disconnecting them.
+func (r *Registry) Range(fn func(session *Session) bool) {
+	for _, session := range r.GetSessions() {
+		if !fn(session) {
+			return
+		}
+	}
+}
+
+// Count returns the amount of sessions in the registry.
+func (r *Registry) Count() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.sessions)
+}
+
+// Broadcast writes the packet to the clients of all sessions for which the filter returns true, or of all the
+// sessions of the registry if the filter is nil.
+func (r *Registry) Broadcast(pk packet.Packet, filter func(session *Session) bool) {
+	r.Range(func(session *Session) bool {
+		if filter == nil || filter(session) {
+			_ = session.WritePacket(pk)
+		}
+		return true
+	})
+}
+
+// hooks holds the functions subscribed to a notification of a Registry.
+type hooks[T any] struct {
+	fns    map[uint64]func(T)
+	nextID uint64
+	mu     sync.RWMutex
+}
+
+// add subscribes the function, returning a function that cancels the subscription.
+func (h *hooks[T]) add(fn func(T)) (remove func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.fns == nil {
+		h.fns = make(map[uint64]func(T))
+	}
+	id := h.nextID
+	h.nextID++
+	h.fns[id] = fn
+	return func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.fns, id)
+	}
+}
+
+// call calls the subscribed functions with the provided value.
+func (h *hooks[T]) call(v T) {
+	h.mu.RLock()
+	fns := make([]func(T), 0, len(h.fns))
+	for _, fn := range h.fns {
+		fns = append(fns, fn)
+	}
+	h.mu.RUnlock()
+	for _, fn := range fns {
+		fn(v)
+	}
+}
@@ -0,0 +1,135 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/login"
+)
+
+func TestRegistryReserve(t *testing.T) {
+	registered, pending, session := &Session{}, &Session{}, &Session{}
+	tests := []struct {
+		name         string
+		registered   *Session
+		pending      *Session
+		others       int
+		rejectNew    bool
+		limit        int
+		wantExisting int
+		wantErr      error
+	}{
+		{name: "no session"},
+		{name: "no session rejecting new", rejectNew: true},
+		{name: "registered", registered: registered, wantExisting: 1},
+		{name: "pending", pending: pending, wantExisting: 1},
+		{name: "registered and pending", registered: registered, pending: pending, wantExisting: 2},
+		{name: "registered rejecting new", registered: registered, rejectNew: true, wantExisting: 1, wantErr: errAlreadyLoggedIn},
+		{name: "pending rejecting new", pending: pending, rejectNew: true, wantExisting: 1, wantErr: errAlreadyLoggedIn},
+		{name: "reserved by itself", pending: session, rejectNew: true},
+		{name: "below limit", others: 1, limit: 2},
+		{name: "limit reached", others: 2, limit: 2, wantErr: ErrProxyFull},
+		{name: "limit reached replacing session", registered: registered, pending: pending, others: 1, limit: 2, wantExisting: 2},
+		{name: "limit reached rejecting new", registered: registered, others: 2, rejectNew: true, limit: 2, wantExisting: 1, wantErr: errAlreadyLoggedIn},
+		{name: "unlimited", others: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRegistry()
+			if tt.registered != nil {
+				r.sessions["xuid"] = tt.registered
+			}
+			if tt.pending != nil {
+				r.pending["xuid"] = tt.pending
+			}
+			// The sessions of other players are split between the ones registered and the ones logging in.
+			for i := range tt.others {
+				if i%2 == 0 {
+					r.sessions[fmt.Sprint("other", i)] = &Session{}
+				} else {
+					r.pending[fmt.Sprint("other", i)] = &Session{}
+				}
+			}
+
+			existing, err := r.reserve("xuid", session, tt.rejectNew, tt.limit)
+			if len(existing) != tt.wantExisting || !errors.Is(err, tt.wantErr) {
+				t.Fatalf("reserve() = %v, %v, want %v sessions, %v", existing, err, tt.wantExisting, tt.wantErr)
+			}
+			if want := err == nil || tt.pending == session; (r.pending["xuid"] == session) != want {
+				t.Fatalf("XUID reserved = %v, want %v", !want, want)
+			}
+		})
+	}
+}
+
+func TestRegistryRelease(t *testing.T) {
+	first, second := &Session{}, &Session{}
+	r := NewRegistry()
+	r.reserve("xuid", first, false, 0)
+	r.reserve("xuid", second, false, 0)
+
+	r.release("xuid", first)
+	if r.pending["xuid"] != second {
+		t.Fatal("release() released the reservation of a newer login")
+	}
+	if r.register("xuid", first) {
+		t.Fatal("register() registered a session that lost its reservation")
+	}
+
+	r.release("xuid", second)
+	if _, ok := r.pending["xuid"]; ok {
+		t.Fatal("release() kept the reservation")
+	}
+}
+
+func TestRegistryIndexes(t *testing.T) {
+	steveID, stevensonID := uuid.New(), uuid.New()
+	steve := &Session{client: newTestIdentityClient(t, login.IdentityData{DisplayName: "Steve", Identity: steveID.String()}).conn, serverAddr: "a"}
+	stevenson := &Session{client: newTestIdentityClient(t, login.IdentityData{DisplayName: "Stevenson", Identity: stevensonID.String()}).conn, serverAddr: "a"}
+	alex := &Session{client: newTestIdentityClient(t, login.IdentityData{DisplayName: "Alex"}).conn, serverAddr: "b"}
+	r := NewRegistry()
+	r.AddSession("steve", steve)
+	r.AddSession("stevenson", stevenson)
+	r.AddSession("alex", alex)
+
+	lookups := []struct {
+		name   string
+		lookup func() *Session
+		want   *Session
+	}{
+		{name: "name", lookup: func() *Session { return r.GetSessionByName("Steve") }, want: steve},
+		{name: "name ignoring case", lookup: func() *Session { return r.GetSessionByName("sTEVENSON") }, want: stevenson},
+		{name: "unknown name", lookup: func() *Session { return r.GetSessionByName("Ste") }},
+		{name: "prefix", lookup: func() *Session { return r.GetSessionByPrefix("al") }, want: alex},
+		{name: "shortest prefix match", lookup: func() *Session { return r.GetSessionByPrefix("STE") }, want: steve},
+		{name: "longer prefix", lookup: func() *Session { return r.GetSessionByPrefix("steven") }, want: stevenson},
+		{name: "unknown prefix", lookup: func() *Session { return r.GetSessionByPrefix("bob") }},
+		{name: "uuid", lookup: func() *Session { return r.GetSessionByUUID(stevensonID) }, want: stevenson},
+		{name: "unknown uuid", lookup: func() *Session { return r.GetSessionByUUID(uuid.New()) }},
+	}
+	for _, tt := range lookups {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.lookup(); got != tt.want {
+				t.Fatalf("lookup = %p, want %p", got, tt.want)
+			}
+		})
+	}
+
+	if counts, want := r.ServerCounts(), map[string]int{"a": 2, "b": 1}; !maps.Equal(counts, want) {
+		t.Fatalf("ServerCounts() = %v, want %v", counts, want)
+	}
+
+	r.RemoveSession("steve")
+	if r.GetSessionByName("steve") != nil || r.GetSessionByUUID(steveID) != nil {
+		t.Fatal("RemoveSession() kept the session indexed")
+	}
+	if got := r.GetSessionByPrefix("ste"); got != stevenson {
+		t.Fatalf("GetSessionByPrefix() = %p, want %p", got, stevenson)
+	}
+	if counts, want := r.ServerCounts(), map[string]int{"a": 1, "b": 1}; !maps.Equal(counts, want) {
+		t.Fatalf("ServerCounts() = %v, want %v", counts, want)
+	}
+}
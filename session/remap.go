@@ -0,0 +1,193 @@
+package session
+
+import (
+	"bytes"
+	"slices"
+
+	"github.com/sandertv/gophertunnel/minecraft"
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+)
+
+// remappedPackets holds the IDs of the packets carrying entity IDs rewritten by an idRemap. They are decoded
+// and encoded again when forwarded without requiring the proxy to decode them, but only as long as the IDs
+// of the server differ from the ones of the client.
+var remappedPackets = []uint32{
+	packet.IDActorEvent,
+	packet.IDAddActor,
+	packet.IDAddPlayer,
+	packet.IDAnimate,
+	packet.IDBossEvent,
+	packet.IDEmote,
+	packet.IDInteract,
+	packet.IDInventoryTransaction,
+	packet.IDMobArmourEquipment,
+	packet.IDMobEffect,
+	packet.IDMobEquipment,
+	packet.IDMoveActorAbsolute,
+	packet.IDMoveActorDelta,
+	packet.IDMovePlayer,
+	packet.IDPlayerAction,
+	packet.IDRemoveActor,
+	packet.IDRespawn,
+	packet.IDSetActorData,
+	packet.IDSetActorLink,
+	packet.IDSetActorMotion,
+	packet.IDSetLocalPlayerAsInitialised,
+	packet.IDTakeItemActor,
+	packet.IDUpdateAbilities,
+	packet.IDUpdateAttributes,
+	packet.IDUpdatePlayerGameType,
+}
+
+// idRemap translates the entity IDs of the player between the ID space of the server the session is connected
+// to and the one of the client, which keeps the IDs assigned by the server it logged in to. The IDs are swapped
+// rather than replaced, so that an entity of the server using the player's ID on the client does not collide
+// with the player, and the same translation therefore applies to packets travelling in both directions.
+type idRemap struct {
+	clientRuntimeID, serverRuntimeID uint64
+	clientUniqueID, serverUniqueID   int64
+}
+
+// newIDRemap creates an idRemap translating the entity IDs of the player in the server's game data to the ones
+// in the client's game data.
+func newIDRemap(client, server minecraft.GameData) *idRemap {
+	return &idRemap{
+		clientRuntimeID: client.EntityRuntimeID,
+		serverRuntimeID: server.EntityRuntimeID,
+		clientUniqueID:  client.EntityUniqueID,
+		serverUniqueID:  server.EntityUniqueID,
+	}
+}
+
+// identity reports whether the server uses the same IDs for the player as the client, in which case no ID
+// needs to be rewritten.
+func (r *idRemap) identity() bool {
+	return r.clientRuntimeID == r.serverRuntimeID && r.clientUniqueID == r.serverUniqueID
+}
+
+func (r *idRemap) runtimeID(id *uint64) {
+	switch *id {
+	case r.serverRuntimeID:
+		*id = r.clientRuntimeID
+	case r.clientRuntimeID:
+		*id = r.serverRuntimeID
+	}
+}
+
+func (r *idRemap) uniqueID(id *int64) {
+	switch *id {
+	case r.serverUniqueID:
+		*id = r.clientUniqueID
+	case r.clientUniqueID:
+		*id = r.serverUniqueID
+	}
+}
+
+func (r *idRemap) links(links []protocol.EntityLink) {
+	for i := range links {
+		r.uniqueID(&links[i].RiddenEntityUniqueID)
+		r.uniqueID(&links[i].RiderEntityUniqueID)
+	}
+}
+
+// apply rewrites the entity IDs held by the packet, if it is one of remappedPackets.
+func (r *idRemap) apply(pk packet.Packet) {
+	switch pk := pk.(type) {
+	case *packet.ActorEvent:
+		r.runtimeID(&pk.EntityRuntimeID)
+	case *packet.AddActor:
+		r.uniqueID(&pk.EntityUniqueID)
+		r.runtimeID(&pk.EntityRuntimeID)
+		r.links(pk.EntityLinks)
+	case *packet.AddPlayer:
+		r.runtimeID(&pk.EntityRuntimeID)
+		r.uniqueID(&pk.AbilityData.EntityUniqueID)
+		r.links(pk.EntityLinks)
+	case *packet.Animate:
+		r.runtimeID(&pk.EntityRuntimeID)
+	case *packet.BossEvent:
+		r.uniqueID(&pk.BossEntityUniqueID)
+		r.uniqueID(&pk.PlayerUniqueID)
+	case *packet.Emote:
+		r.runtimeID(&pk.EntityRuntimeID)
+	case *packet.Interact:
+		r.runtimeID(&pk.TargetEntityRuntimeID)
+	case *packet.InventoryTransaction:
+		if data, ok := pk.TransactionData.(*protocol.UseItemOnEntityTransactionData); ok {
+			r.runtimeID(&data.TargetEntityRuntimeID)
+		}
+	case *packet.MobArmourEquipment:
+		r.runtimeID(&pk.EntityRuntimeID)
+	case *packet.MobEffect:
+		r.runtimeID(&pk.EntityRuntimeID)
+	case *packet.MobEquipment:
+		r.runtimeID(&pk.EntityRuntimeID)
+	case *packet.MoveActorAbsolute:
+		r.runtimeID(&pk.EntityRuntimeID)
+	case *packet.MoveActorDelta:
+		r.runtimeID(&pk.EntityRuntimeID)
+	case *packet.MovePlayer:
+		r.runtimeID(&pk.EntityRuntimeID)
+		r.runtimeID(&pk.RiddenEntityRuntimeID)
+	case *packet.PlayerAction:
+		r.runtimeID(&pk.EntityRuntimeID)
+	case *packet.RemoveActor:
+		r.uniqueID(&pk.EntityUniqueID)
+	case *packet.Respawn:
+		r.runtimeID(&pk.EntityRuntimeID)
+	case *packet.SetActorData:
+		r.runtimeID(&pk.EntityRuntimeID)
+	case *packet.SetActorLink:
+		r.uniqueID(&pk.EntityLink.RiddenEntityUniqueID)
+		r.uniqueID(&pk.EntityLink.RiderEntityUniqueID)
+	case *packet.SetActorMotion:
+		r.runtimeID(&pk.EntityRuntimeID)
+	case *packet.SetLocalPlayerAsInitialised:
+		r.runtimeID(&pk.EntityRuntimeID)
+	case *packet.TakeItemActor:
+		r.runtimeID(&pk.ItemEntityRuntimeID)
+		r.runtimeID(&pk.TakerEntityRuntimeID)
+	case *packet.UpdateAbilities:
+		r.uniqueID(&pk.AbilityData.EntityUniqueID)
+	case *packet.UpdateAttributes:
+		r.runtimeID(&pk.EntityRuntimeID)
+	case *packet.UpdatePlayerGameType:
+		r.uniqueID(&pk.PlayerUniqueID)
+	}
+}
+
+// applyRaw rewrites the entity IDs held by an encoded packet, including its packet.Header, by decoding it using
+// the provided protocol and encoding it again. Payloads of packets other than remappedPackets, as well as the
+// ones that could not be decoded, are returned unchanged.
+func (r *idRemap) applyRaw(proto minecraft.Protocol, payload []byte, shieldID int32, client bool) (remapped []byte) {
+	buf := bytes.NewBuffer(payload)
+	header := &packet.Header{}
+	if err := header.Read(buf); err != nil || !slices.Contains(remappedPackets, header.PacketID) {
+		return payload
+	}
+
+	factory, ok := proto.Packets(client)[header.PacketID]
+	if !ok {
+		return payload
+	}
+
+	defer func() {
+		if recover() != nil {
+			remapped = payload
+		}
+	}()
+	pk := factory()
+	pk.Marshal(proto.NewReader(buf, shieldID, client))
+	r.apply(pk)
+	return encodePacket(proto, pk, shieldID)
+}
+
+// entityRemap returns the idRemap translating the entity IDs of the player for the current server, or nil if
+// util.Opts.RemapEntityIDs is disabled or the server uses the same IDs as the client.
+func (s *Session) entityRemap() *idRemap {
+	if r := s.remap.Load(); r != nil && !r.identity() {
+		return r
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package session
+
+import (
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+)
+
+// SendMessage sends a chat message to the player.
+func (s *Session) SendMessage(message string) error {
+	return s.WritePacket(&packet.Text{TextType: packet.TextTypeRaw, Message: message})
+}
+
+// SendPopup sends a popup displayed above the player's hotbar.
+func (s *Session) SendPopup(message string) error {
+	return s.WritePacket(&packet.Text{TextType: packet.TextTypePopup, Message: message})
+}
+
+// SendTip sends a tip displayed in the middle of the player's screen.
+func (s *Session) SendTip(message string) error {
+	return s.WritePacket(&packet.Text{TextType: packet.TextTypeTip, Message: message})
+}
+
+// SendTitle displays a title and subtitle to the player, fading in and out for the provided durations and remaining
+// visible in between. The subtitle is not displayed if it is empty.
+func (s *Session) SendTitle(title, subtitle string, fadeIn, stay, fadeOut time.Duration) error {
+	err := s.WritePacket(&packet.SetTitle{
+		ActionType:      packet.TitleActionSetDurations,
+		FadeInDuration:  durationTicks(fadeIn),
+		RemainDuration:  durationTicks(stay),
+		FadeOutDuration: durationTicks(fadeOut),
+	})
+	if err != nil {
+		return err
+	}
+
+	if subtitle != "" {
+		if err := s.WritePacket(&packet.SetTitle{ActionType: packet.TitleActionSetSubtitle, Text: subtitle}); err != nil {
+			return err
+		}
+	}
+	return s.WritePacket(&packet.SetTitle{ActionType: packet.TitleActionSetTitle, Text: title})
+}
+
+// SendToast displays a toast notification with a title and a message at the top of the player's screen.
+func (s *Session) SendToast(title, message string) error {
+	return s.WritePacket(&packet.ToastRequest{Title: title, Message: message})
+}
+
+// PlaySound plays the sound with the name, such as "random.orb", to the player at the position.
+func (s *Session) PlaySound(name string, pos mgl32.Vec3, volume, pitch float32) error {
+	return s.WritePacket(&packet.PlaySound{SoundName: name, Position: pos, Volume: volume, Pitch: pitch})
+}
+
+// durationTicks converts the duration to game ticks, each lasting 50 milliseconds.
+func durationTicks(duration time.Duration) int32 {
+	return int32(duration / (time.Millisecond * 50))
+}
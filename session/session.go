@@ -4,7 +4,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"math"
+	"math/rand/v2"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -13,11 +17,20 @@ import (
 	"github.com/cooldogedev/spectrum/session/animation"
 	"github.com/cooldogedev/spectrum/transport"
 	"github.com/cooldogedev/spectrum/util"
+	"github.com/go-gl/mathgl/mgl32"
 	"github.com/sandertv/gophertunnel/minecraft"
 	"github.com/sandertv/gophertunnel/minecraft/protocol"
 	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
 )
 
+// ErrProxyFull is returned when a login is rejected because util.Opts.MaxSessions sessions are already online or
+// logging in.
+var ErrProxyFull = errors.New("proxy is full")
+
+// errAlreadyLoggedIn is returned when a login is rejected because the player is already logged in and
+// util.Opts.DuplicateLoginPolicy rejects new logins.
+var errAlreadyLoggedIn = errors.New("already logged in")
+
 // Session represents a player session within the proxy, managing client and server interactions,
 // including transfers, fallbacks, and tracking various session states.
 type Session struct {
@@ -34,19 +47,44 @@ type Session struct {
 	registry *Registry
 
 	discovery server.Discovery
-	opts      util.Opts
-	transport transport.Transport
+	opts      atomic.Pointer[util.Opts]
+
+	transport   transport.Transport
+	transportMu sync.RWMutex
 
-	animation animation.Animation
-	tracker   *tracker
+	animation             animation.Animation
+	animationDisabled     atomic.Bool
+	destinationAnimations map[string]animation.Animation
+	destinationMu         sync.RWMutex
+	tracker               *tracker
+	remap                 atomic.Pointer[idRemap]
 
 	processor   Processor
+	processors  []*processorEntry
 	processorMu sync.RWMutex
 
+	loginTiming    atomic.Pointer[ConnectionTiming]
+	transferTiming atomic.Pointer[ConnectionTiming]
+
+	transferHistory transferHistory
+	lastTransfer    atomic.Int64
+	transferQueue   transferQueue
+	transferReset   atomic.Pointer[TransferResetFunc]
+	transferState   atomic.Int32
+
+	store      Store
+	deferred   deferredPackets
+	capture    atomic.Pointer[capture]
+	rawTap     atomic.Pointer[server.RawTap]
 	cache      atomic.Value
 	latency    atomic.Int64
+	stats      networkStats
+	latencies  latencyHistory
 	inFallback atomic.Bool
+	loggedIn   atomic.Bool
+	inLimbo    atomic.Bool
 	once       sync.Once
+	wg         sync.WaitGroup
 }
 
 // NewSession creates a new Session instance using the provided minecraft.Conn.
@@ -58,7 +96,6 @@ func NewSession(client *minecraft.Conn, logger *slog.Logger, registry *Registry,
 		registry: registry,
 
 		discovery: discovery,
-		opts:      opts,
 		transport: transport,
 
 		processor: NopProcessor{},
@@ -67,6 +104,7 @@ func NewSession(client *minecraft.Conn, logger *slog.Logger, registry *Registry,
 		tracker:   newTracker(),
 	}
 	s.ctx, s.cancelFunc = context.WithCancelCause(client.Context())
+	s.opts.Store(&opts)
 	s.cache.Store([]byte(nil))
 	return s
 }
@@ -90,45 +128,93 @@ func (s *Session) LoginTimeout(duration time.Duration) (err error) {
 // using the provided context for cancellation.
 func (s *Session) LoginContext(ctx context.Context) (err error) {
 	identityData := s.client.IdentityData()
-	serverAddr, err := s.discovery.Discover(s.client)
+	if filter := s.opts.Load().LoginFilter; filter != nil {
+		if err := filter(s.client); err != nil {
+			s.logger.Debug("login filter rejected session", "err", err)
+			return err
+		}
+	}
+
+	// The XUID is reserved before the session is registered, so that concurrent logins of the same player are
+	// detected as well, and so that concurrent logins are counted towards util.Opts.MaxSessions.
+	opts := s.opts.Load()
+	limit := opts.MaxSessions
+	if limit > 0 && opts.MaxSessionsExempt != nil && opts.MaxSessionsExempt(s.client) {
+		limit = 0
+	}
+	existing, err := s.registry.reserve(identityData.XUID, s, opts.DuplicateLoginPolicy == util.DuplicateLoginPolicyRejectNew, limit)
+	if errors.Is(err, ErrProxyFull) {
+		s.logger.Debug("rejected login, proxy is full", "sessions", opts.MaxSessions)
+		return withMessage(util.MessageProxyFull, "", err)
+	} else if err != nil {
+		s.logger.Debug("rejected duplicate login")
+		return err
+	}
+	defer func() {
+		if err != nil {
+			s.registry.release(identityData.XUID, s)
+		}
+	}()
+	for _, existing := range existing {
+		existing.CloseWithError(withMessage(util.MessageLoggedInElsewhere, "", errors.New("logged in from another location")))
+	}
+
+	result, err := s.discover(s.discovery, false)
 	if err != nil {
 		s.logger.Debug("discovery failed", "err", err)
-		return err
+		if s.opts.Load().LimboOnNoBackend {
+			return s.enterLimbo(ctx)
+		}
+		return withMessage(util.MessageDiscoveryFailed, "", err)
 	}
 
-	conn, err := s.dial(ctx, serverAddr)
+	timing := &ConnectionTiming{}
+	start := time.Now()
+	conn, serverAddr, err := s.establishLoginServer(ctx, result.Target(), timing)
 	if err != nil {
-		s.logger.Debug("dialer failed", "err", err)
-		return err
+		s.logger.Debug("failed to establish server connection", "addr", serverAddr, "err", err)
+		if s.opts.Load().LimboOnNoBackend {
+			return s.enterLimbo(ctx)
+		}
+		return loginError(ctx, util.MessageDialFailed, serverAddr, err)
 	}
 
+	s.wg.Add(3)
 	go handleServer(s)
 	go handleClient(s)
-	go handleLatency(s, s.opts.LatencyInterval)
-	if err := conn.DoConnect(); err != nil {
-		s.logger.Debug("connection sequence failed", "err", err)
-		return err
-	}
+	go handleLatency(s)
 
 	if err := conn.WaitConnect(ctx); err != nil {
 		conn.CloseWithError(fmt.Errorf("connection sequence failed: %w", err))
-		s.logger.Debug("connection sequence failed", "err", err)
-		return err
+		s.logger.Debug("connection sequence failed", "addr", serverAddr, "err", err)
+		return loginError(ctx, util.MessageDialFailed, serverAddr, err)
 	}
+	timing.Connect = time.Since(start) - timing.Dial
 
+	spawnStart := time.Now()
 	gameData := conn.GameData()
+	if err := validateGameData(gameData); err != nil {
+		conn.CloseWithError(err)
+		s.logger.Debug("server sent invalid game data", "addr", serverAddr, "err", err)
+		return loginError(ctx, util.MessageInvalidGameData, serverAddr, err)
+	}
+
 	s.Processor().ProcessStartGame(NewContext(), &gameData)
 	if err := s.client.StartGame(gameData); err != nil {
-		s.logger.Debug("startgame sequence failed", "err", err)
-		return err
+		s.logger.Debug("startgame sequence failed", "addr", serverAddr, "err", err)
+		return loginError(ctx, util.MessageSpawnFailed, serverAddr, err)
 	}
 
 	if err := conn.DoSpawn(); err != nil {
-		s.logger.Debug("spawn sequence failed", "err", err)
-		return err
+		s.logger.Debug("spawn sequence failed", "addr", serverAddr, "err", err)
+		return loginError(ctx, util.MessageSpawnFailed, serverAddr, err)
+	}
+	timing.Spawn = time.Since(spawnStart)
+	s.loginTiming.Store(timing)
+	if !s.registry.register(identityData.XUID, s) {
+		return errors.New("logged in from another location")
 	}
-	s.registry.AddSession(identityData.XUID, s)
-	s.logger.Info("logged in session")
+	s.logger.Info("logged in session", "addr", serverAddr)
 	return
 }
 
@@ -152,48 +238,387 @@ func (s *Session) TransferTimeout(addr string, duration time.Duration) (err erro
 // occurs at a time, returning an error if another transfer is already in progress.
 // The process is performed using the provided context for cancellation.
 func (s *Session) TransferContext(ctx context.Context, addr string) (err error) {
+	return s.transfer(ctx, addr, transferRequest{})
+}
+
+// TransferToPosition initiates a transfer to a different server using the specified address, moving the player
+// to the provided position and rotation instead of the server's spawn position once the transfer completes.
+// The override only affects the initial client-side reposition, the server remains authoritative over movement.
+// It sets a default timeout of 1 minute for the transfer operation.
+func (s *Session) TransferToPosition(addr string, pos mgl32.Vec3, yaw, pitch float32) (err error) {
+	position := &transferPosition{pos: pos, yaw: yaw, pitch: pitch}
+	if !position.finite() {
+		return errors.New("invalid transfer position")
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, time.Minute)
+	defer cancel()
+	return s.transfer(ctx, addr, transferRequest{position: position})
+}
+
+// TransferWithProgress initiates a transfer to a different server using the specified address, calling onState
+// every time the state of the transfer changes. The last state reported is always either TransferStateCompleted
+// or TransferStateFailed, unless the transfer is rejected before it starts.
+// It sets a default timeout of 1 minute for the transfer operation.
+func (s *Session) TransferWithProgress(addr string, onState func(state TransferState)) (err error) {
+	ctx, cancel := context.WithTimeout(s.ctx, time.Minute)
+	defer cancel()
+	return s.transfer(ctx, addr, transferRequest{onState: onState})
+}
+
+// TransferWithOptions initiates a transfer to a different server using the specified address and options. Transfer
+// uses DefaultTransferOptions. It sets a default timeout of 1 minute for the transfer operation, unless the options
+// specify a timeout.
+func (s *Session) TransferWithOptions(addr string, opts TransferOptions) (err error) {
+	timeout := time.Minute
+	if opts.Timeout > 0 {
+		timeout = opts.Timeout
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, timeout)
+	defer cancel()
+	return s.TransferWithOptionsContext(ctx, addr, opts)
+}
+
+// TransferWithOptionsContext initiates a transfer to a different server using the specified address and options.
+// The process is performed using the provided context for cancellation, limited by the timeout of the options if set.
+func (s *Session) TransferWithOptionsContext(ctx context.Context, addr string, opts TransferOptions) (err error) {
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+	return s.transfer(ctx, addr, transferRequest{options: &opts})
+}
+
+// transfer initiates a transfer to a different server using the specified address and request parameters.
+// The connection sequence and the spawn of the player continue once transfer has returned, and they are
+// aborted if they are still in progress by the deadline of the provided context.
+func (s *Session) transfer(ctx context.Context, addr string, req transferRequest) (err error) {
+	var (
+		finished atomic.Bool
+		prepared bool
+	)
 	s.serverMu.RLock()
 	origin := s.serverAddr
 	s.serverMu.RUnlock()
+	defer func() {
+		if err != nil && finished.CompareAndSwap(false, true) {
+			if prepared {
+				s.Processor().ProcessTransferFailure(NewContext(), &origin, &addr, err)
+			}
+			req.complete(err)
+		}
+	}()
+
 	processorCtx := NewContext()
 	s.Processor().ProcessPreTransfer(processorCtx, &origin, &addr)
 	if processorCtx.Cancelled() {
+		if message := processorCtx.Message(); message != "" {
+			s.sendMessage(message)
+			return fmt.Errorf("processor failed: %s", message)
+		}
 		return errors.New("processor failed")
 	}
+	prepared = true
 
-	s.sendMetadata(true)
-	conn, err := s.dial(ctx, addr)
-	if err != nil {
-		s.Processor().ProcessTransferFailure(NewContext(), &origin, &addr)
-		return fmt.Errorf("dialer failed: %w", err)
+	if s.clientClosed() {
+		return fmt.Errorf("client closed: %w", context.Cause(s.client.Context()))
 	}
 
-	if err := conn.DoConnect(); err != nil {
-		s.Processor().ProcessTransferFailure(NewContext(), &origin, &addr)
-		return fmt.Errorf("connection sequence failed failed: %w", err)
+	if missing := s.missingResourcePacks(addr); len(missing) > 0 {
+		return &ResourcePackMismatchError{Addr: addr, Missing: missing}
+	}
+
+	if cooldown := time.Millisecond * time.Duration(s.opts.Load().TransferCooldown); cooldown > 0 && !req.force {
+		if last := s.lastTransfer.Load(); last != 0 && time.Since(time.UnixMilli(last)) < cooldown {
+			return ErrTransferCooldown
+		}
+	}
+
+	if !s.beginTransfer() {
+		return errors.New("already transferring")
+	}
+
+	if opts := s.opts.Load(); opts.TransferLoopThreshold > 0 {
+		window := time.Millisecond * time.Duration(opts.TransferLoopWindow)
+		if !s.transferHistory.visit(addr, window, opts.TransferLoopThreshold) {
+			// The refusal is completed with ErrTransferLoop by the deferred function above.
+			s.setTransferState(req, TransferStateFailed)
+			if opts.TransferLoopMessage != "" {
+				s.sendMessage(opts.TransferLoopMessage)
+			}
+			s.logger.Warn("refused transfer due to a transfer loop", "origin", origin, "target", addr)
+			return ErrTransferLoop
+		}
 	}
+	s.lastTransfer.Store(time.Now().UnixMilli())
 
-	conn.OnConnect(func(err error) {
+	s.registry.publish(EventPreTransfer{session: s, Origin: origin, Target: addr})
+	s.setTransferState(req, TransferStateDialing)
+	s.sendMetadata(true)
+	_ = s.Flush()
+	timing := &ConnectionTiming{}
+	start := time.Now()
+	fail := func(err error) bool {
+		if !finished.CompareAndSwap(false, true) {
+			return false
+		}
+		s.setTransferState(req, TransferStateFailed)
+		s.Processor().ProcessTransferFailure(NewContext(), &origin, &addr, err)
+		req.complete(err)
+		return true
+	}
+	conn, err := s.establishServer(ctx, addr, timing, func(conn *server.Conn, err error) {
 		if err != nil {
-			s.Processor().ProcessTransferFailure(NewContext(), &origin, &addr)
+			fail(err)
 			return
 		}
 
+		timing.Connect = time.Since(start) - timing.Dial
+		spawnStart := time.Now()
 		gameData := conn.GameData()
-		s.animation.Play(s.client, gameData)
-		s.sendGameData(conn.GameData())
+		if err := validateGameData(gameData); err != nil {
+			fail(err)
+			s.logger.Debug("server sent invalid game data", "target", addr, "err", err)
+			conn.CloseWithError(err)
+			return
+		}
+
+		if s.clientClosed() {
+			fail(fmt.Errorf("client closed: %w", context.Cause(s.client.Context())))
+			return
+		}
+
+		if s.opts.Load().RemapEntityIDs {
+			// The client keeps the IDs it was assigned at login, the player is moved using them.
+			r := newIDRemap(s.client.GameData(), gameData)
+			s.remap.Store(r)
+			gameData.EntityRuntimeID, gameData.EntityUniqueID = r.clientRuntimeID, r.clientUniqueID
+		} else {
+			s.remap.Store(nil)
+		}
+
+		// The toggle is read once so that an animation that was played is always cleared.
+		animate := s.AnimationEnabled()
+		s.setTransferState(req, TransferStateResetting)
+		if !req.transferOptions().KeepCamera {
+			// The camera is reset before the animation is played, as animations may set the camera themselves.
+			s.tracker.mu.Lock()
+			s.tracker.clearCamera(s)
+			s.tracker.mu.Unlock()
+		}
+		anim := s.transferAnimation(addr, req.group)
+		if animate {
+			anim.Play(s.client, gameData)
+		}
+		s.sendGameData(gameData, req)
+		if s.clientClosed() {
+			// The client disconnected while its state was being reset, there is no point in spawning it.
+			fail(fmt.Errorf("client closed: %w", context.Cause(s.client.Context())))
+			return
+		}
+
+		s.setTransferState(req, TransferStateSpawning)
 		if err := conn.DoSpawn(); err != nil {
-			s.Processor().ProcessTransferFailure(NewContext(), &origin, &addr)
+			if lifecycle, ok := anim.(animation.Lifecycle); ok && animate {
+				lifecycle.Abort(s.client)
+			}
+			fail(fmt.Errorf("spawn sequence failed: %w", err))
 			return
 		}
 		s.inFallback.Store(false)
-		s.animation.Clear(s.client, gameData)
+		s.leaveLimbo()
+		if animate {
+			if lifecycle, ok := anim.(animation.Lifecycle); ok {
+				lifecycle.Spawned(s.client, gameData)
+			}
+			anim.Clear(s.client, gameData)
+		}
+
+		if !finished.CompareAndSwap(false, true) {
+			// The transfer timed out while the player was being spawned.
+			return
+		}
+		timing.Spawn = time.Since(spawnStart)
+		s.transferTiming.Store(timing)
+		s.setTransferState(req, TransferStateCompleted)
 		s.Processor().ProcessPostTransfer(NewContext(), &origin, &addr)
+		s.registry.publish(EventPostTransfer{session: s, Origin: origin, Target: addr})
 		s.logger.Debug("transferred session", "origin", origin, "target", addr)
+		req.complete(nil)
 	})
+	if err != nil {
+		fail(err)
+		s.serverMu.RLock()
+		current := s.serverAddr
+		s.serverMu.RUnlock()
+		if req.options != nil && req.options.Fallback && current == origin {
+			// The player is still connected to the previous server. Failures after it was replaced are already
+			// followed by a fallback once the new connection is closed.
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				if err := s.fallback(); err != nil {
+					logError(s, "fallback failed", err)
+				}
+			}()
+		}
+		return err
+	}
+
+	// The deadline of the context no longer applies once transfer returns, so the rest of the transfer is aborted
+	// if it has not finished by then, or by the default transfer timeout if the context has no deadline. Otherwise,
+	// a server that never responds would leave the transfer in progress indefinitely, refusing every later transfer.
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = start.Add(defaultTransferTimeout)
+	}
+	time.AfterFunc(time.Until(deadline), func() {
+		if fail(ErrTransferTimeout) {
+			s.logger.Debug("transfer timed out", "target", addr)
+			conn.CloseWithError(ErrTransferTimeout)
+		}
+	})
+
+	// The connection sequence may in theory complete before this point is reached, in which case the
+	// transfer has already moved past the connecting state.
+	if s.transferState.CompareAndSwap(int32(TransferStateDialing), int32(TransferStateConnecting)) && req.onState != nil {
+		req.onState(TransferStateConnecting)
+	}
 	return nil
 }
 
+// forceTransfer initiates a transfer to a different server using the specified address, ignoring the transfer
+// cooldown. It is used for transfers initiated by the proxy itself, such as fallbacks, and sets a default timeout
+// of 1 minute for the transfer operation. The group is the name of the server group the server was discovered
+// from, if any. The provided function, which may be nil, is called once the transfer has either completed or failed.
+func (s *Session) forceTransfer(addr string, group string, onComplete func(err error)) (err error) {
+	ctx, cancel := context.WithTimeout(s.ctx, time.Minute)
+	defer cancel()
+	req := transferRequest{force: true, group: group}
+	if onComplete != nil {
+		options := DefaultTransferOptions()
+		options.OnComplete = onComplete
+		req.options = &options
+	}
+	return s.transfer(ctx, addr, req)
+}
+
+// ScheduleTransfer schedules a transfer to the specified address, performed on a separate goroutine once the
+// session's current transfer, if any, has finished. Unlike Transfer, it is safe to call from processor hooks
+// that run as part of a transfer or on the session's packet handling goroutines. Errors are logged.
+func (s *Session) ScheduleTransfer(addr string) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		if err := s.waitTransfer(); err != nil {
+			return
+		}
+
+		if err := s.Transfer(addr); err != nil {
+			logError(s, "failed to transfer", err)
+		}
+	}()
+}
+
+// Reconnect discovers a server for the session and connects it, resetting the client's state as done for transfers.
+// It is intended for sessions which are no longer connected to a live server, such as after a server outage,
+// and returns an error if the session's current server connection is still alive.
+func (s *Session) Reconnect() (err error) {
+	if conn := s.Server(); conn != nil {
+		select {
+		case <-conn.Context().Done():
+		default:
+			return errors.New("session is connected to a server")
+		}
+	}
+
+	result, err := s.discover(s.discovery, false)
+	if err != nil {
+		return fmt.Errorf("discovery failed: %w", err)
+	}
+	s.logger.Debug("reconnecting session", "addr", result.Target())
+	return s.forceTransfer(result.Target(), result.Group, nil)
+}
+
+// TransferToDiscovery runs the provided discovery for the session and transfers it to the resulting server.
+// If discovery is nil, the session's own discovery is used. An error is returned if the discovered
+// server is the one the session is currently connected to.
+func (s *Session) TransferToDiscovery(discovery server.Discovery) (err error) {
+	if discovery == nil {
+		discovery = s.discovery
+	}
+
+	result, err := s.discover(discovery, false)
+	if err != nil {
+		return fmt.Errorf("discovery failed: %w", err)
+	}
+
+	s.serverMu.RLock()
+	origin := s.serverAddr
+	s.serverMu.RUnlock()
+	if result.Target() == origin {
+		return errors.New("discovered server is the current server")
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, time.Minute)
+	defer cancel()
+	return s.transfer(ctx, result.Target(), transferRequest{group: result.Group})
+}
+
+// TransferToGroup transfers the session to the best server of the named group, such as "lobby", as determined
+// by the session's discovery. An error is returned if the discovery does not implement server.GroupDiscovery.
+func (s *Session) TransferToGroup(group string) (err error) {
+	discovery, ok := s.discovery.(server.GroupDiscovery)
+	if !ok {
+		return server.ErrGroupsUnsupported
+	}
+
+	addr, err := discovery.DiscoverGroup(s.client, group)
+	if err != nil {
+		return fmt.Errorf("discovery failed: %w", err)
+	}
+	s.logger.Debug("transferring session to group", "group", group, "addr", addr)
+	ctx, cancel := context.WithTimeout(s.ctx, time.Minute)
+	defer cancel()
+	return s.transfer(ctx, addr, transferRequest{group: group})
+}
+
+// SetTransferResetFunc sets the function replacing the packets sent to reset the player's state during transfers,
+// such as the player's position, the weather, difficulty, game mode and game rules. The empty chunks, the clearing
+// of tracked state and the animation are unaffected. Passing nil restores the default reset.
+func (s *Session) SetTransferResetFunc(fn TransferResetFunc) {
+	if fn == nil {
+		s.transferReset.Store(nil)
+		return
+	}
+	s.transferReset.Store(&fn)
+}
+
+// LastLoginTiming returns the timing of the session's login sequence. A zero ConnectionTiming is returned if the
+// session has not logged in to a server yet.
+func (s *Session) LastLoginTiming() ConnectionTiming {
+	if timing := s.loginTiming.Load(); timing != nil {
+		return *timing
+	}
+	return ConnectionTiming{}
+}
+
+// LastTransferTiming returns the timing of the session's last completed transfer. A zero ConnectionTiming is
+// returned if the session has not been transferred yet.
+func (s *Session) LastTransferTiming() ConnectionTiming {
+	if timing := s.transferTiming.Load(); timing != nil {
+		return *timing
+	}
+	return ConnectionTiming{}
+}
+
+// TransferState returns the state of the session's current or last transfer.
+func (s *Session) TransferState() TransferState {
+	return TransferState(s.transferState.Load())
+}
+
 // Animation returns the animation set to be played during server transfers.
 func (s *Session) Animation() animation.Animation {
 	return s.animation
@@ -204,6 +629,102 @@ func (s *Session) SetAnimation(animation animation.Animation) {
 	s.animation = animation
 }
 
+// SetDestinationAnimation sets the animation played instead of the session's animation during transfers to the
+// destination, which is either the address of a server or the name of a server group transferred to using
+// TransferToGroup. Animations set for an address take precedence over the ones set for a group. Passing a nil
+// animation removes the animation of the destination.
+func (s *Session) SetDestinationAnimation(destination string, anim animation.Animation) {
+	s.destinationMu.Lock()
+	defer s.destinationMu.Unlock()
+	if anim == nil {
+		delete(s.destinationAnimations, destination)
+		return
+	}
+
+	if s.destinationAnimations == nil {
+		s.destinationAnimations = make(map[string]animation.Animation)
+	}
+	s.destinationAnimations[destination] = anim
+}
+
+// AnimationEnabled reports whether the session's animation is played during server transfers.
+func (s *Session) AnimationEnabled() bool {
+	return !s.animationDisabled.Load()
+}
+
+// SetAnimationEnabled sets whether the session's animation is played during server transfers. Animations are
+// enabled by default.
+func (s *Session) SetAnimationEnabled(enabled bool) {
+	s.animationDisabled.Store(!enabled)
+}
+
+// Opts returns a copy of the session's configuration options.
+func (s *Session) Opts() util.Opts {
+	return *s.opts.Load()
+}
+
+// SetOpts replaces the session's configuration options. It is safe to call while the session is running,
+// the new options are picked up by the session's goroutines the next time they are consulted.
+func (s *Session) SetOpts(opts util.Opts) {
+	s.opts.Store(&opts)
+}
+
+// Transport returns the transport used for dialing servers.
+func (s *Session) Transport() transport.Transport {
+	s.transportMu.RLock()
+	defer s.transportMu.RUnlock()
+	return s.transport
+}
+
+// SetTransport sets the transport used for dialing servers. The new transport is used by the next dial,
+// allowing a processor to switch transports based on the destination before a transfer.
+func (s *Session) SetTransport(transport transport.Transport) {
+	s.transportMu.Lock()
+	s.transport = transport
+	s.transportMu.Unlock()
+}
+
+// Migrate sets the transport used for dialing servers and moves the session to a new connection to its current
+// server dialed using the new transport. The handoff is performed the same way as a transfer, so the player
+// stays on the same server while the previous connection is replaced.
+func (s *Session) Migrate(transport transport.Transport) (err error) {
+	s.serverMu.RLock()
+	addr := s.serverAddr
+	s.serverMu.RUnlock()
+	s.SetTransport(transport)
+	s.logger.Debug("migrating session to a new transport", "addr", addr)
+	return s.forceTransfer(addr, "", nil)
+}
+
+// StartCapture starts recording the packets passing through the session in both directions to the provided
+// io.Writer. Each packet is written with its direction and a timestamp, and the recording can be read back
+// using a CaptureReader. The recording stops once StopCapture is called or writing to w fails.
+func (s *Session) StartCapture(w io.Writer) error {
+	if !s.capture.CompareAndSwap(nil, newCapture(w)) {
+		return errors.New("already capturing")
+	}
+	return nil
+}
+
+// StopCapture stops the recording previously started using StartCapture.
+func (s *Session) StopCapture() {
+	s.capture.Store(nil)
+}
+
+// SetRawServerTap sets a tap observing the raw frames exchanged with the server before they are decoded, for the
+// current server connection and the ones established by future transfers. Passing nil removes the current tap.
+func (s *Session) SetRawServerTap(tap server.RawTap) {
+	if tap == nil {
+		s.rawTap.Store(nil)
+	} else {
+		s.rawTap.Store(&tap)
+	}
+
+	if conn := s.Server(); conn != nil {
+		conn.SetRawTap(tap)
+	}
+}
+
 // Cache returns the current session cache.
 func (s *Session) Cache() []byte {
 	return s.cache.Load().([]byte)
@@ -218,18 +739,54 @@ func (s *Session) SetCache(cache []byte) {
 	}
 }
 
-// Processor returns the current processor.
+// Store returns the key/value store attached to the session.
+func (s *Session) Store() *Store {
+	return &s.store
+}
+
+// Processor returns the current processor, executing all processors of the session in order.
 func (s *Session) Processor() Processor {
 	s.processorMu.RLock()
 	defer s.processorMu.RUnlock()
 	return s.processor
 }
 
-// SetProcessor sets a new processor for the session.
+// SetProcessor sets a new processor for the session, replacing all processors added using AddProcessor. It is
+// safe to call while the session is running, passing nil resets the session's processor to a NopProcessor.
 func (s *Session) SetProcessor(processor Processor) {
 	s.processorMu.Lock()
-	s.processor = processor
-	s.processorMu.Unlock()
+	defer s.processorMu.Unlock()
+	s.processors = nil
+	if processor != nil {
+		s.processors = append(s.processors, newProcessorEntry(processor, 0))
+	}
+	s.processor = newProcessor(s.processors)
+}
+
+// AddProcessor adds a processor to the chain of processors of the session. Processors with a lower priority are
+// called first, and processors with equal priorities are called in the order they were added. Every processor
+// may modify the packets passed on to the next one, and cancelling the context stops the chain. The function
+// returned removes the processor from the chain. It is safe to call while the session is running.
+func (s *Session) AddProcessor(processor Processor, priority int) (remove func()) {
+	entry := newProcessorEntry(processor, priority)
+	s.processorMu.Lock()
+	defer s.processorMu.Unlock()
+	i := slices.IndexFunc(s.processors, func(e *processorEntry) bool {
+		return e.priority > priority
+	})
+	if i == -1 {
+		i = len(s.processors)
+	}
+	s.processors = slices.Insert(slices.Clone(s.processors), i, entry)
+	s.processor = newProcessor(s.processors)
+	return func() {
+		s.processorMu.Lock()
+		defer s.processorMu.Unlock()
+		if i := slices.Index(s.processors, entry); i != -1 {
+			s.processors = slices.Delete(slices.Clone(s.processors), i, i+1)
+			s.processor = newProcessor(s.processors)
+		}
+	}
 }
 
 // Latency returns the total latency experienced by the session, combining client and server latencies.
@@ -239,6 +796,17 @@ func (s *Session) Latency() int64 {
 	return (s.client.Latency().Milliseconds() * 2) + s.latency.Load()
 }
 
+// NetworkStats returns statistics about the session's connection computed from its most recent latency probes.
+func (s *Session) NetworkStats() NetworkStats {
+	return s.stats.stats(s.latencies.stats().Jitter)
+}
+
+// LatencyStats returns statistics about the session's most recent latency samples, taken every time the latency
+// of the session is updated.
+func (s *Session) LatencyStats() LatencyStats {
+	return s.latencies.stats()
+}
+
 // Client returns the client connection.
 func (s *Session) Client() *minecraft.Conn {
 	return s.client
@@ -251,12 +819,25 @@ func (s *Session) Server() *server.Conn {
 	return s.serverConn
 }
 
+// Flush flushes the packets buffered by the client connection, sending them to the client immediately instead
+// of waiting for the connection's next periodic flush. Packets written to the client are batched by the
+// connection until flushed, and closing the session flushes them before the client connection is closed.
+func (s *Session) Flush() error {
+	return s.client.Flush()
+}
+
 // Context returns the connection's context. The context is canceled when the session is closed,
 // allowing for cancellation of operations that are tied to the lifecycle of the session.
 func (s *Session) Context() context.Context {
 	return s.ctx
 }
 
+// Wait blocks until the session is closed and all the goroutines started by its login sequence have exited.
+func (s *Session) Wait() {
+	<-s.ctx.Done()
+	s.wg.Wait()
+}
+
 // Disconnect sends a packet.Disconnect to the client and closes the session.
 func (s *Session) Disconnect(message string) {
 	s.CloseWithError(errors.New(message))
@@ -270,7 +851,7 @@ func (s *Session) Close() (err error) {
 
 func (s *Session) CloseWithError(err error) {
 	s.once.Do(func() {
-		message := err.Error()
+		message := s.disconnectMessage(err)
 		s.Processor().ProcessDisconnection(NewContext(), &message)
 		_ = s.client.WritePacket(&packet.Disconnect{Message: message})
 		_ = s.client.Close()
@@ -278,11 +859,70 @@ func (s *Session) CloseWithError(err error) {
 			conn.CloseWithError(err)
 		}
 		s.cancelFunc(err)
-		s.registry.RemoveSession(s.client.IdentityData().XUID)
+		s.registry.release(s.client.IdentityData().XUID, s)
+		s.registry.removeSession(s.client.IdentityData().XUID, s)
+		if s.loggedIn.Load() {
+			// Sessions closed before they logged in were never added to the registry.
+			s.registry.publish(EventDisconnect{session: s, Err: err})
+		}
 		s.logger.Info("closed session", "err", err)
 	})
 }
 
+// establishServer dials the specified server address and initiates the connection sequence with it, replacing
+// the session's current server connection. The duration of the dial is recorded in the provided timing, and the
+// provided function, which may be nil, is called once the connection sequence completes or fails.
+func (s *Session) establishServer(ctx context.Context, addr string, timing *ConnectionTiming, onConnect func(conn *server.Conn, err error)) (*server.Conn, error) {
+	start := time.Now()
+	conn, err := s.dial(ctx, addr)
+	if err != nil {
+		return nil, fmt.Errorf("dialer failed: %w", err)
+	}
+	timing.Dial = time.Since(start)
+
+	if onConnect != nil {
+		conn.OnConnect(func(err error) {
+			onConnect(conn, err)
+		})
+	}
+
+	if err := conn.DoConnect(); err != nil {
+		// The failure is reported to the caller through the returned error rather than onConnect.
+		conn.OnConnect(nil)
+		conn.CloseWithError(fmt.Errorf("connection sequence failed: %w", err))
+		return nil, fmt.Errorf("connection sequence failed: %w", err)
+	}
+	return conn, nil
+}
+
+// establishLoginServer establishes a connection to the server discovered during login. If it could not be
+// established, up to util.Opts.LoginFallbackAttempts servers returned by Discovery.DiscoverFallback are tried in
+// order, stopping early once the discovery returns a server that has already been tried. The address of the server
+// connected to is returned, or the one of the last server tried if none could be connected to.
+func (s *Session) establishLoginServer(ctx context.Context, addr string, timing *ConnectionTiming) (*server.Conn, string, error) {
+	conn, err := s.establishServer(ctx, addr, timing, nil)
+	tried := []string{addr}
+	for attempt := 0; err != nil && attempt < s.opts.Load().LoginFallbackAttempts && ctx.Err() == nil; attempt++ {
+		s.logger.Debug("failed to establish server connection, trying a fallback server", "addr", addr, "err", err)
+		result, discoverErr := s.discover(s.discovery, true)
+		if discoverErr != nil {
+			s.logger.Debug("fallback discovery failed", "err", discoverErr)
+			break
+		}
+
+		fallbackAddr := result.Target()
+
+		if slices.Contains(tried, fallbackAddr) {
+			break
+		}
+
+		addr = fallbackAddr
+		tried = append(tried, addr)
+		conn, err = s.establishServer(ctx, addr, timing, nil)
+	}
+	return conn, addr, err
+}
+
 // dial dials the specified server address and returns a new server.Conn instance.
 // The provided context is used to manage timeouts and cancellations during the dialing process.
 func (s *Session) dial(ctx context.Context, addr string) (*server.Conn, error) {
@@ -292,90 +932,398 @@ func (s *Session) dial(ctx context.Context, addr string) (*server.Conn, error) {
 	default:
 	}
 
+	// The server is dialed without holding serverMu, so that a hung server does not block the session, and
+	// the previous connection is only replaced once the new one has been dialed successfully.
+	opts := s.opts.Load()
+	ctx = transport.WithClientAddr(ctx, s.client.RemoteAddr(), s.client.LocalAddr())
+	conn, err := s.dialTransport(ctx, addr, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.ServerReadRate > 0 || opts.ServerWriteRate > 0 {
+		conn = transport.Throttle(conn, opts.ServerReadRate, opts.ServerWriteRate)
+	}
+
+	c := server.NewConn(conn, s.client, s.logger.With("addr", addr), opts.SyncProtocol, s.Cache(), opts.ServerPool)
+	if tap := s.rawTap.Load(); tap != nil {
+		c.SetRawTap(*tap)
+	}
+
 	s.serverMu.Lock()
-	defer s.serverMu.Unlock()
 	if s.serverConn != nil {
 		_ = s.serverConn.Close()
 	}
-
-	conn, err := s.transport.Dial(ctx, addr)
-	if err != nil {
-		return nil, err
-	}
-	c := server.NewConn(conn, s.client, s.logger.With("addr", addr), s.opts.SyncProtocol, s.Cache())
 	s.serverAddr = addr
 	s.serverConn = c
+	s.serverMu.Unlock()
+	s.registry.updateServer(s, addr)
 	return c, nil
 }
 
-// fallback attempts to transfer the session to a fallback server provided by the discovery.
-func (s *Session) fallback() error {
+// dialTransport dials the specified server address using the session's transport, retrying failed dials
+// according to the provided options.
+func (s *Session) dialTransport(ctx context.Context, addr string, opts *util.Opts) (io.ReadWriteCloser, error) {
+	backoff := time.Millisecond * time.Duration(opts.DialBackoff)
+	attempts := max(opts.DialAttempts, 1)
+	for attempt := 1; ; attempt++ {
+		conn, err := s.dialOnce(ctx, addr, time.Millisecond*time.Duration(opts.DialTimeout))
+		if err == nil {
+			return conn, nil
+		}
+
+		if attempt == attempts || ctx.Err() != nil {
+			return nil, &DialError{Addr: addr, Attempts: attempt, Err: err}
+		}
+
+		delay := backoff
+		if opts.DialJitter > 0 {
+			delay += time.Duration(rand.Float64() * opts.DialJitter * float64(backoff))
+		}
+		s.logger.Debug("dial failed, retrying", "addr", addr, "attempt", attempt, "delay", delay, "err", err)
+		select {
+		case <-ctx.Done():
+			return nil, &DialError{Addr: addr, Attempts: attempt, Err: err}
+		case <-time.After(delay):
+		}
+
+		backoff *= 2
+		if maxBackoff := time.Millisecond * time.Duration(opts.DialBackoffMax); maxBackoff > 0 && backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// dialOnce dials the specified server address using the session's transport, limiting the dial to the
+// provided timeout if it is greater than 0.
+func (s *Session) dialOnce(ctx context.Context, addr string, timeout time.Duration) (io.ReadWriteCloser, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return s.Transport().Dial(ctx, addr)
+}
+
+// clearServer removes the provided server connection from the session if it is still the current one.
+func (s *Session) clearServer(conn *server.Conn) {
+	s.serverMu.Lock()
+	cleared := s.serverConn == conn
+	if cleared {
+		s.serverConn = nil
+	}
+	s.serverMu.Unlock()
+	if cleared {
+		// The session is no longer connected to any server, it is removed from the server index.
+		s.registry.updateServer(s, "")
+	}
+}
+
+// fallback attempts to transfer the session to a fallback server provided by the discovery, making up to
+// util.Opts.FallbackAttempts attempts before giving up.
+func (s *Session) fallback() (err error) {
 	select {
 	case <-s.ctx.Done():
 		return context.Cause(s.ctx)
 	default:
 	}
 
+	opts := s.opts.Load()
+	if opts.FallbackAttempts < 0 {
+		return errors.New("fallback disabled")
+	}
+
 	if !s.inFallback.CompareAndSwap(false, true) {
 		return errors.New("already in fallback")
 	}
+	defer func() {
+		if err != nil {
+			s.inFallback.Store(false)
+		}
+	}()
 
-	addr, err := s.discovery.DiscoverFallback(s.client)
-	if err != nil {
-		return fmt.Errorf("discovery failed: %w", err)
+	for attempt := 1; attempt <= max(opts.FallbackAttempts, 1); attempt++ {
+		if attempt > 1 {
+			select {
+			case <-s.ctx.Done():
+				return context.Cause(s.ctx)
+			case <-time.After(time.Millisecond * time.Duration(opts.FallbackRetryDelay)):
+			}
+		}
+
+		var result server.DiscoveryResult
+		result, err = s.discover(s.discovery, true)
+		if err != nil {
+			err = fmt.Errorf("discovery failed: %w", err)
+			s.logger.Debug("fallback attempt failed", "attempt", attempt, "err", err)
+			continue
+		}
+
+		s.logger.Debug("transferring session to a fallback server", "addr", result.Target(), "attempt", attempt)
+		// The fallback ends once the transfer completes or fails, which may only happen after forceTransfer
+		// returned, as the rest of the transfer continues asynchronously.
+		if err = s.forceTransfer(result.Target(), result.Group, func(error) { s.inFallback.Store(false) }); err == nil {
+			return nil
+		}
+		// The transfer failed before it started, leaving the fallback in progress for the next attempt.
+		s.inFallback.Store(true)
+		err = fmt.Errorf("transfer failed: %w", err)
+		s.logger.Debug("fallback attempt failed", "attempt", attempt, "err", err)
 	}
+	return err
+}
 
-	s.logger.Debug("transferring session to a fallback server", "addr", addr)
-	if err := s.Transfer(addr); err != nil {
-		return fmt.Errorf("transfer failed: %w", err)
+// discover runs the provided discovery for the session, returning either the primary or the fallback server. If
+// the discovery does not implement server.ResultDiscovery, the result only holds the address of the server.
+func (s *Session) discover(discovery server.Discovery, fallback bool) (server.DiscoveryResult, error) {
+	if d, ok := discovery.(server.ResultDiscovery); ok {
+		if fallback {
+			return d.DiscoverFallbackResult(s.client)
+		}
+		return d.DiscoverResult(s.client)
+	}
+
+	var (
+		addr string
+		err  error
+	)
+	if fallback {
+		addr, err = discovery.DiscoverFallback(s.client)
+	} else {
+		addr, err = discovery.Discover(s.client)
+	}
+	return server.DiscoveryResult{Addr: addr}, err
+}
+
+// clientClosed reports whether the client connection has been closed, in which case the session is closing.
+func (s *Session) clientClosed() bool {
+	select {
+	case <-s.client.Context().Done():
+		return true
+	default:
+		return false
+	}
+}
+
+// beginTransfer marks the start of a transfer, returning false if another transfer is already in progress.
+func (s *Session) beginTransfer() bool {
+	for {
+		state := s.transferState.Load()
+		if TransferState(state).InProgress() {
+			return false
+		}
+
+		if s.transferState.CompareAndSwap(state, int32(TransferStateDialing)) {
+			return true
+		}
+	}
+}
+
+// setTransferState updates the state of the current transfer and reports it to the request's callback.
+func (s *Session) setTransferState(req transferRequest, state TransferState) {
+	s.transferState.Store(int32(state))
+	switch state {
+	case TransferStateDialing:
+		s.sendTransferTitle()
+	case TransferStateCompleted, TransferStateFailed:
+		s.clearTransferTitle()
+		s.flushDeferred()
+	}
+
+	if req.onState != nil {
+		req.onState(state)
+	}
+}
+
+// transferAnimation returns the animation played during a transfer to the provided address, discovered from the
+// provided group if not empty.
+func (s *Session) transferAnimation(addr, group string) animation.Animation {
+	s.destinationMu.RLock()
+	defer s.destinationMu.RUnlock()
+	if anim, ok := s.destinationAnimations[addr]; ok {
+		return anim
+	}
+
+	if anim, ok := s.destinationAnimations[group]; ok && group != "" {
+		return anim
+	}
+	return s.animation
+}
+
+// sendMessage sends a raw chat message to the player.
+func (s *Session) sendMessage(message string) {
+	_ = s.client.WritePacket(&packet.Text{TextType: packet.TextTypeRaw, Message: message})
+}
+
+// sendTransferTitle displays the configured transfer title and subtitle to the player until they are cleared.
+func (s *Session) sendTransferTitle() {
+	opts := s.opts.Load()
+	if opts.TransferTitle == "" && opts.TransferSubtitle == "" {
+		return
+	}
+
+	_ = s.client.WritePacket(&packet.SetTitle{ActionType: packet.TitleActionSetDurations, RemainDuration: math.MaxInt32})
+	_ = s.client.WritePacket(&packet.SetTitle{ActionType: packet.TitleActionSetSubtitle, Text: opts.TransferSubtitle})
+	_ = s.client.WritePacket(&packet.SetTitle{ActionType: packet.TitleActionSetTitle, Text: opts.TransferTitle})
+}
+
+// clearTransferTitle clears the title previously displayed using sendTransferTitle.
+func (s *Session) clearTransferTitle() {
+	if opts := s.opts.Load(); opts.TransferTitle == "" && opts.TransferSubtitle == "" {
+		return
+	}
+	_ = s.client.WritePacket(&packet.SetTitle{ActionType: packet.TitleActionClear})
+	_ = s.client.WritePacket(&packet.SetTitle{ActionType: packet.TitleActionReset})
+}
+
+// capturePacket writes the payload to the provided capture, stopping the capture if writing fails.
+func (s *Session) capturePacket(c *capture, direction int, payload []byte) {
+	if err := c.write(direction, payload); err != nil {
+		s.capture.CompareAndSwap(c, nil)
+		logError(s, "failed to write captured packet", err)
 	}
-	return nil
 }
 
 func (s *Session) sendMetadata(noAI bool) {
+	_ = s.client.WritePacket(&packet.SetActorData{
+		EntityRuntimeID: s.client.GameData().EntityRuntimeID,
+		EntityMetadata:  playerMetadata(noAI, s.opts.Load().EntityDataFlags),
+	})
+}
+
+// playerMetadata returns the metadata sent by sendMetadata, holding the default flags of the player along with the
+// additional flags provided.
+func playerMetadata(noAI bool, flags []uint8) protocol.EntityMetadata {
 	metadata := protocol.NewEntityMetadata()
 	if noAI {
 		metadata.SetFlag(protocol.EntityDataKeyFlags, protocol.EntityDataFlagNoAI)
 	}
 	metadata.SetFlag(protocol.EntityDataKeyFlags, protocol.EntityDataFlagBreathing)
 	metadata.SetFlag(protocol.EntityDataKeyFlags, protocol.EntityDataFlagHasGravity)
-	_ = s.client.WritePacket(&packet.SetActorData{
-		EntityRuntimeID: s.client.GameData().EntityRuntimeID,
-		EntityMetadata:  metadata,
-	})
+	for _, flag := range flags {
+		setEntityFlag(metadata, flag)
+	}
+	return metadata
+}
+
+// mergeMetadata sets the NoAI flag set by sendMetadata in actor data sent by the server for the player while a
+// transfer is in progress, so that the player does not move before being spawned in the new server. Actor data
+// sent otherwise is passed through unchanged, leaving the server in control of the player's flags.
+func (s *Session) mergeMetadata(pk *packet.SetActorData) {
+	if !s.TransferState().InProgress() || pk.EntityRuntimeID != s.client.GameData().EntityRuntimeID {
+		return
+	}
+	setEntityFlag(pk.EntityMetadata, protocol.EntityDataFlagNoAI)
+}
+
+// setEntityFlag sets the flag in the metadata if it is not set already. Flags with an index of 64 or above
+// are stored in protocol.EntityDataKeyFlagsTwo, and flags are only set if their key holds a flag field.
+func setEntityFlag(metadata protocol.EntityMetadata, flag uint8) {
+	key := uint32(protocol.EntityDataKeyFlags)
+	if flag >= 64 {
+		key, flag = protocol.EntityDataKeyFlagsTwo, flag-64
+	}
+
+	if _, ok := metadata[key].(int64); ok && !metadata.Flag(key, flag) {
+		metadata.SetFlag(key, flag)
+	}
 }
 
-func (s *Session) sendGameData(gameData minecraft.GameData) {
-	chunk := emptyChunk(gameData.Dimension)
-	pos := gameData.PlayerPosition
+// sendChunks sends the chunks surrounding the provided position, which are empty unless a
+// util.Opts.TransferChunkProvider is set.
+func (s *Session) sendChunks(dimension int32, pos mgl32.Vec3) {
+	chunk := emptyChunk(dimension)
+	provider := s.opts.Load().TransferChunkProvider
 	chunkX := int32(pos.X()) >> 4
 	chunkZ := int32(pos.Z()) >> 4
 	for x := chunkX - 4; x <= chunkX+4; x++ {
 		for z := chunkZ - 4; z <= chunkZ+4; z++ {
+			payload, subChunkCount := chunk, uint32(1)
+			if provider != nil {
+				payload, subChunkCount = provider(dimension, x, z)
+			}
+
 			_ = s.client.WritePacket(&packet.LevelChunk{
-				Dimension:     gameData.Dimension,
+				Dimension:     dimension,
 				Position:      protocol.ChunkPos{x, z},
-				SubChunkCount: 1,
-				RawPayload:    chunk,
+				SubChunkCount: subChunkCount,
+				RawPayload:    payload,
 			})
 		}
 	}
+}
+
+func (s *Session) sendGameData(gameData minecraft.GameData, req transferRequest) {
+	s.sendChunks(gameData.Dimension, gameData.PlayerPosition)
+	options := req.transferOptions()
+
 	s.tracker.mu.Lock()
-	s.tracker.clearEffects(s)
-	s.tracker.clearEntities(s)
-	s.tracker.clearBossBars(s)
-	s.tracker.clearPlayers(s)
-	s.tracker.clearScoreboards(s)
+	if !options.KeepCommands {
+		s.tracker.clearCommands(s)
+	}
+	if !options.KeepEffects {
+		s.tracker.clearEffects(s)
+	}
+	if !options.KeepEntities {
+		s.tracker.clearEntities(s)
+	}
+	if !options.KeepFog {
+		s.tracker.clearFog(s)
+	}
+	if !options.KeepForms {
+		s.tracker.clearForms(s)
+	}
+	if !options.KeepBossBars {
+		s.tracker.clearBossBars(s)
+	}
+	if !options.KeepInventories {
+		s.tracker.clearInventories(s)
+	}
+	if !options.KeepPlayers {
+		s.tracker.clearPlayers(s)
+	}
+	if !options.KeepScoreboards {
+		s.tracker.clearScoreboards(s)
+	}
 	s.tracker.mu.Unlock()
-	_ = s.client.WritePacket(&packet.MovePlayer{
+	if fn := s.transferReset.Load(); fn != nil {
+		(*fn)(s, gameData)
+	} else {
+		s.resetGameData(gameData, req)
+	}
+	// The packets above are only buffered by the connection, flushing them here sends the whole
+	// reset sequence in a single batch rather than waiting for the connection's next flush tick.
+	_ = s.Flush()
+}
+
+// resetGameData resets the player's position and world state to the ones of the provided game data.
+func (s *Session) resetGameData(gameData minecraft.GameData, req transferRequest) {
+	movePlayer := &packet.MovePlayer{
 		EntityRuntimeID: gameData.EntityRuntimeID,
 		Position:        gameData.PlayerPosition,
 		Pitch:           gameData.Pitch,
 		Yaw:             gameData.Yaw,
-		Mode:            packet.MoveModeReset,
-	})
-	_ = s.client.WritePacket(&packet.LevelEvent{EventType: packet.LevelEventStopRaining, EventData: 10_000})
-	_ = s.client.WritePacket(&packet.LevelEvent{EventType: packet.LevelEventStopThunderstorm})
+		HeadYaw:         gameData.Yaw,
+		Mode:            s.opts.Load().TransferMoveMode,
+	}
+	if position := req.position; position != nil {
+		if position.within(gameData.Dimension) {
+			movePlayer.Position = position.pos
+			movePlayer.Pitch = position.pitch
+			movePlayer.Yaw = position.yaw
+			movePlayer.HeadYaw = position.yaw
+		} else {
+			s.logger.Debug("transfer position is out of the world bounds, using the server position", "pos", position.pos)
+		}
+	}
+	if movePlayer.Mode == packet.MoveModeTeleport {
+		movePlayer.TeleportCause = packet.TeleportCauseCommand
+	}
+	_ = s.client.WritePacket(movePlayer)
+	if gameData.Dimension == packet.DimensionOverworld {
+		_ = s.client.WritePacket(&packet.LevelEvent{EventType: packet.LevelEventStopRaining, EventData: 10_000})
+		_ = s.client.WritePacket(&packet.LevelEvent{EventType: packet.LevelEventStopThunderstorm})
+	}
 	_ = s.client.WritePacket(&packet.SetDifficulty{Difficulty: uint32(gameData.Difficulty)})
 	_ = s.client.WritePacket(&packet.SetPlayerGameType{GameType: gameData.PlayerGameMode})
 	_ = s.client.WritePacket(&packet.GameRulesChanged{GameRules: gameData.GameRules})
@@ -0,0 +1,1198 @@
+package session
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"math"
+	"net"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cooldogedev/spectrum/protocol"
+	"github.com/cooldogedev/spectrum/server"
+	spectrumpacket "github.com/cooldogedev/spectrum/server/packet"
+	"github.com/cooldogedev/spectrum/util"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/golang/snappy"
+	"github.com/sandertv/gophertunnel/minecraft"
+	gtprotocol "github.com/sandertv/gophertunnel/minecraft/protocol"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/login"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+)
+
+// testServerGameData is the game data served by a testBackend.
+var testServerGameData = minecraft.GameData{
+	EntityRuntimeID: 1,
+	EntityUniqueID:  1,
+	Dimension:       packet.DimensionOverworld,
+	WorldSpawn:      gtprotocol.BlockPos{0, 64, 0},
+	PlayerPosition:  mgl32.Vec3{0.5, 64, 0.5},
+	Yaw:             90,
+	Pitch:           10,
+}
+
+// testTransport is a transport connecting sessions to an in-memory server served by its function. Dials fail
+// if it has no function, and they are counted by dials if set.
+type testTransport struct {
+	serve func(conn net.Conn)
+	dials *atomic.Int32
+}
+
+// Dial ...
+func (t testTransport) Dial(context.Context, string) (io.ReadWriteCloser, error) {
+	if t.dials != nil {
+		t.dials.Add(1)
+	}
+	if t.serve == nil {
+		return nil, errors.New("connection refused")
+	}
+
+	conn, serverConn := net.Pipe()
+	go t.serve(serverConn)
+	return conn, nil
+}
+
+// testBackend returns a function serving the connection sequence of the game data to a session, followed by the
+// frames provided, and discarding the packets sent by the session. The connection sequence is only served once
+// start is closed, if it is not nil. A nil frame closes the connection.
+func testBackend(gameData minecraft.GameData, start <-chan struct{}, frames ...[]byte) func(conn net.Conn) {
+	return func(conn net.Conn) {
+		defer conn.Close()
+		reader, writer := protocol.NewReader(conn), protocol.NewWriter(conn)
+		if _, err := reader.ReadPacket(); err != nil {
+			return
+		}
+
+		// The session writes to the server during the connection sequence, so that the packets are read while
+		// the connection sequence is being written.
+		go func() {
+			if start != nil {
+				<-start
+			}
+
+			for _, frame := range append(testSequence(gameData), frames...) {
+				if frame == nil {
+					_ = conn.Close()
+					return
+				}
+				if err := writer.Write(frame); err != nil {
+					return
+				}
+			}
+		}()
+
+		for {
+			if _, err := reader.ReadPacket(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// testSequence returns the frames of the connection sequence of a server with the game data.
+func testSequence(gameData minecraft.GameData) [][]byte {
+	return [][]byte{
+		testFrame(&spectrumpacket.ConnectionResponse{RuntimeID: gameData.EntityRuntimeID, UniqueID: gameData.EntityUniqueID}),
+		testFrame(&packet.StartGame{
+			Dimension:      gameData.Dimension,
+			WorldSpawn:     gameData.WorldSpawn,
+			PlayerPosition: gameData.PlayerPosition,
+			Yaw:            gameData.Yaw,
+			Pitch:          gameData.Pitch,
+		}),
+		testFrame(&packet.ItemRegistry{}),
+		testFrame(&packet.ChunkRadiusUpdated{ChunkRadius: 8}),
+		testFrame(&packet.PlayStatus{Status: packet.PlayStatusPlayerSpawn}),
+	}
+}
+
+// testFrame encodes the packet as a frame sent by a server, to be decoded by the proxy.
+func testFrame(pk packet.Packet) []byte {
+	buf := &bytes.Buffer{}
+	header := &packet.Header{PacketID: pk.ID()}
+	_ = header.Write(buf)
+	pk.Marshal(minecraft.DefaultProtocol.NewWriter(buf, 0))
+	return append([]byte{0}, snappy.Encode(nil, buf.Bytes())...)
+}
+
+// testClient is a client that logged in to a listener without authentication.
+type testClient struct {
+	// conn is the connection of the client accepted by the listener.
+	conn   *minecraft.Conn
+	dialed chan *minecraft.Conn
+}
+
+// newTestClient returns a client that logged in to a listener without authentication. The client does not spawn
+// until the game is started on its connection.
+func newTestClient(t *testing.T) *testClient {
+	t.Helper()
+	return newTestIdentityClient(t, login.IdentityData{})
+}
+
+// newTestIdentityClient returns a client like newTestClient, logging in with the identity data.
+func newTestIdentityClient(t *testing.T, identityData login.IdentityData) *testClient {
+	t.Helper()
+	listener, err := minecraft.ListenConfig{AuthenticationDisabled: true}.Listen("raknet", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	c := &testClient{dialed: make(chan *minecraft.Conn, 1)}
+	go func() {
+		if conn, err := (minecraft.Dialer{IdentityData: identityData}).DialContext(ctx, "raknet", listener.Addr().String()); err == nil {
+			t.Cleanup(func() { _ = conn.Close() })
+			c.dialed <- conn
+		}
+	}()
+
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatalf("failed to accept client: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	c.conn = conn.(*minecraft.Conn)
+	return c
+}
+
+// remote returns the connection of the client to the listener, once it has spawned.
+func (c *testClient) remote(t *testing.T) *minecraft.Conn {
+	t.Helper()
+	select {
+	case conn := <-c.dialed:
+		c.dialed <- conn
+		return conn
+	case <-time.After(time.Second * 10):
+		t.Fatal("client did not spawn")
+		return nil
+	}
+}
+
+// readUntil reads packets from the connection until the function returns true for one of them, returning all
+// packets read.
+func readUntil(t *testing.T, conn *minecraft.Conn, fn func(pk packet.Packet) bool) []packet.Packet {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second * 10))
+	var pks []packet.Packet
+	for {
+		pk, err := conn.ReadPacket()
+		if err != nil {
+			t.Fatalf("failed to read packet: %v", err)
+		}
+
+		pks = append(pks, pk)
+		if fn(pk) {
+			return pks
+		}
+	}
+}
+
+// transferPackets performs the transfer and returns the packets received by the client until it has finished.
+func transferPackets(t *testing.T, s *Session, client *testClient, transfer func() error) ([]packet.Packet, error) {
+	t.Helper()
+	err := transfer()
+	if err := s.waitTransfer(); err != nil {
+		t.Fatalf("session closed: %v", err)
+	}
+
+	// The packets sent once the transfer is finished are preceded by the ones of the transfer.
+	s.sendMessage("transferred")
+	_ = s.Flush()
+	pks := readUntil(t, client.remote(t), func(pk packet.Packet) bool {
+		text, ok := pk.(*packet.Text)
+		return ok && text.Message == "transferred"
+	})
+	return pks[:len(pks)-1], err
+}
+
+// waitFor waits until the condition is met, failing the test if it is not met within 10 seconds.
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second * 10)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition was not met in time")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+// packetsOf returns the packets of the type T among the packets.
+func packetsOf[T packet.Packet](pks []packet.Packet) []T {
+	var matches []T
+	for _, pk := range pks {
+		if pk, ok := pk.(T); ok {
+			matches = append(matches, pk)
+		}
+	}
+	return matches
+}
+
+// newTestSession returns a session of a spawned test client, reading packets from the servers it is connected to
+// through the transport until the test finishes.
+func newTestSession(t *testing.T, opts util.Opts, transport testTransport) (*Session, *testClient) {
+	t.Helper()
+	client := newTestClient(t)
+	if err := client.conn.StartGame(testServerGameData); err != nil {
+		t.Fatalf("failed to start game: %v", err)
+	}
+	client.remote(t)
+
+	s := newTestClientSession(t, client, opts, NewRegistry(), nil, transport)
+	s.wg.Add(1)
+	go handleServer(s)
+	return s, client
+}
+
+// newTestLoginSession returns a session of a test client that has not logged in yet, registered in the registry
+// once it logs in to a server returned by the discovery.
+func newTestLoginSession(t *testing.T, opts util.Opts, registry *Registry, discovery server.Discovery, transport testTransport) (*Session, *testClient) {
+	t.Helper()
+	client := newTestClient(t)
+	return newTestClientSession(t, client, opts, registry, discovery, transport), client
+}
+
+// newTestClientSession returns a session of the client, which is closed once the test finishes.
+func newTestClientSession(t *testing.T, client *testClient, opts util.Opts, registry *Registry, discovery server.Discovery, transport testTransport) *Session {
+	t.Helper()
+	s := NewSession(client.conn, slog.New(slog.NewTextHandler(io.Discard, nil)), registry, discovery, opts, transport)
+	t.Cleanup(func() {
+		_ = s.Close()
+		s.wg.Wait()
+	})
+	return s
+}
+
+// testDiscovery is a discovery returning the same server, or an error if set, for every discovery. Discoveries
+// are counted by discoveries if set.
+type testDiscovery struct {
+	addr        string
+	err         error
+	discoveries *atomic.Int32
+}
+
+// Discover ...
+func (d testDiscovery) Discover(*minecraft.Conn) (string, error) {
+	if d.discoveries != nil {
+		d.discoveries.Add(1)
+	}
+	return d.addr, d.err
+}
+
+// DiscoverFallback ...
+func (d testDiscovery) DiscoverFallback(*minecraft.Conn) (string, error) {
+	return d.addr, d.err
+}
+
+func TestSessionTransferToDiscovery(t *testing.T) {
+	tests := []struct {
+		name      string
+		discovery testDiscovery
+		wantAddr  string
+		wantErr   bool
+	}{
+		{name: "discovered server", discovery: testDiscovery{addr: "lobby"}, wantAddr: "lobby"},
+		{name: "discovery failed", discovery: testDiscovery{err: errors.New("no server")}, wantAddr: "origin", wantErr: true},
+		{name: "current server", discovery: testDiscovery{addr: "origin"}, wantAddr: "origin", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestSession(t, *util.DefaultOpts(), testTransport{serve: testBackend(testServerGameData, nil)})
+			s.serverAddr = "origin"
+			err := s.TransferToDiscovery(tt.discovery)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TransferToDiscovery() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err := s.waitTransfer(); err != nil {
+				t.Fatalf("session closed: %v", err)
+			}
+
+			s.serverMu.RLock()
+			addr := s.serverAddr
+			s.serverMu.RUnlock()
+			if addr != tt.wantAddr {
+				t.Fatalf("session connected to %q, want %q", addr, tt.wantAddr)
+			}
+			if !tt.wantErr && s.TransferState() != TransferStateCompleted {
+				t.Fatalf("TransferState() = %v, want %v", s.TransferState(), TransferStateCompleted)
+			}
+		})
+	}
+}
+
+func TestSessionTransferMoveMode(t *testing.T) {
+	tests := []struct {
+		name      string
+		mode      byte
+		wantCause int32
+	}{
+		{name: "teleport", mode: packet.MoveModeTeleport, wantCause: packet.TeleportCauseCommand},
+		{name: "reset", mode: packet.MoveModeReset},
+		{name: "normal", mode: packet.MoveModeNormal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := *util.DefaultOpts()
+			opts.TransferMoveMode = tt.mode
+			s, client := newTestSession(t, opts, testTransport{serve: testBackend(testServerGameData, nil)})
+			pks, err := transferPackets(t, s, client, func() error { return s.Transfer("server") })
+			if err != nil {
+				t.Fatalf("Transfer() error = %v", err)
+			}
+
+			moves := packetsOf[*packet.MovePlayer](pks)
+			if len(moves) != 1 {
+				t.Fatalf("client received %v MovePlayer packets, want 1", len(moves))
+			}
+			move := moves[0]
+			if move.Mode != tt.mode || move.TeleportCause != tt.wantCause {
+				t.Fatalf("MovePlayer mode = %v, cause = %v, want %v, %v", move.Mode, move.TeleportCause, tt.mode, tt.wantCause)
+			}
+			if move.Position != testServerGameData.PlayerPosition || move.Yaw != testServerGameData.Yaw || move.Pitch != testServerGameData.Pitch {
+				t.Fatalf("MovePlayer moved to %v (%v, %v), want the server's %v (%v, %v)", move.Position, move.Yaw, move.Pitch,
+					testServerGameData.PlayerPosition, testServerGameData.Yaw, testServerGameData.Pitch)
+			}
+		})
+	}
+}
+
+func TestSessionSetTransport(t *testing.T) {
+	tests := []struct {
+		name        string
+		swap        bool
+		wantOld     int32
+		wantSwapped int32
+	}{
+		{name: "kept", wantOld: 2},
+		{name: "swapped", swap: true, wantOld: 1, wantSwapped: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old := testTransport{serve: testBackend(testServerGameData, nil), dials: &atomic.Int32{}}
+			swapped := testTransport{serve: testBackend(testServerGameData, nil), dials: &atomic.Int32{}}
+			opts := *util.DefaultOpts()
+			opts.TransferCooldown = 0
+			s, _ := newTestSession(t, opts, old)
+			for i, addr := range []string{"first", "second"} {
+				if i == 1 && tt.swap {
+					s.SetTransport(swapped)
+				}
+				if err := s.Transfer(addr); err != nil {
+					t.Fatalf("Transfer() error = %v", err)
+				}
+				if err := s.waitTransfer(); err != nil {
+					t.Fatalf("session closed: %v", err)
+				}
+			}
+
+			if old.dials.Load() != tt.wantOld || swapped.dials.Load() != tt.wantSwapped {
+				t.Fatalf("transports dialed %v and %v times, want %v and %v", old.dials.Load(), swapped.dials.Load(), tt.wantOld, tt.wantSwapped)
+			}
+			if s.TransferState() != TransferStateCompleted {
+				t.Fatalf("TransferState() = %v, want %v", s.TransferState(), TransferStateCompleted)
+			}
+		})
+	}
+}
+
+func TestSessionTransferToPosition(t *testing.T) {
+	tests := []struct {
+		name       string
+		pos        mgl32.Vec3
+		yaw, pitch float32
+		wantPos    mgl32.Vec3
+		wantYaw    float32
+		wantErr    bool
+	}{
+		{name: "override", pos: mgl32.Vec3{100, 70, -100}, yaw: 180, pitch: 5, wantPos: mgl32.Vec3{100, 70, -100}, wantYaw: 180},
+		{name: "out of bounds", pos: mgl32.Vec3{0, 1000, 0}, yaw: 180, wantPos: testServerGameData.PlayerPosition, wantYaw: testServerGameData.Yaw},
+		{name: "not finite", pos: mgl32.Vec3{float32(math.NaN()), 70, 0}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, client := newTestSession(t, *util.DefaultOpts(), testTransport{serve: testBackend(testServerGameData, nil)})
+			pks, err := transferPackets(t, s, client, func() error { return s.TransferToPosition("server", tt.pos, tt.yaw, tt.pitch) })
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TransferToPosition() error = %v, want error %v", err, tt.wantErr)
+			}
+
+			moves := packetsOf[*packet.MovePlayer](pks)
+			if tt.wantErr {
+				if len(moves) != 0 {
+					t.Fatal("client was moved by a refused transfer")
+				}
+				return
+			}
+			if len(moves) != 1 {
+				t.Fatalf("client received %v MovePlayer packets, want 1", len(moves))
+			}
+			if moves[0].Position != tt.wantPos || moves[0].Yaw != tt.wantYaw || moves[0].HeadYaw != tt.wantYaw {
+				t.Fatalf("MovePlayer moved to %v (%v), want %v (%v)", moves[0].Position, moves[0].Yaw, tt.wantPos, tt.wantYaw)
+			}
+		})
+	}
+}
+
+func TestSessionResetGameDataOrder(t *testing.T) {
+	s, client := newTestSession(t, *util.DefaultOpts(), testTransport{serve: testBackend(testServerGameData, nil)})
+	s.SetAnimationEnabled(false)
+	pks, err := transferPackets(t, s, client, func() error { return s.Transfer("server") })
+	if err != nil {
+		t.Fatalf("Transfer() error = %v", err)
+	}
+
+	want := []uint32{
+		packet.IDLevelChunk,
+		packet.IDMovePlayer,
+		packet.IDLevelEvent,
+		packet.IDLevelEvent,
+		packet.IDSetDifficulty,
+		packet.IDSetPlayerGameType,
+		packet.IDGameRulesChanged,
+	}
+	var got []uint32
+	for _, pk := range pks {
+		if slices.Contains(want, pk.ID()) {
+			got = append(got, pk.ID())
+		}
+	}
+	// The chunks surrounding the player are all sent before the reset packets.
+	got = slices.CompactFunc(got, func(a, b uint32) bool {
+		return a == packet.IDLevelChunk && b == packet.IDLevelChunk
+	})
+	if !slices.Equal(got, want) {
+		t.Fatalf("client received reset packets %v, want %v", got, want)
+	}
+}
+
+func TestSessionTransferTitle(t *testing.T) {
+	tests := []struct {
+		name      string
+		title     string
+		transport testTransport
+		want      []int32
+	}{
+		{name: "no title", transport: testTransport{serve: testBackend(testServerGameData, nil)}, want: []int32{-1}},
+		{
+			name:      "completed",
+			title:     "Transferring",
+			transport: testTransport{serve: testBackend(testServerGameData, nil)},
+			want: []int32{
+				packet.TitleActionSetDurations,
+				packet.TitleActionSetSubtitle,
+				packet.TitleActionSetTitle,
+				-1,
+				packet.TitleActionClear,
+				packet.TitleActionReset,
+			},
+		},
+		{
+			name:  "failed",
+			title: "Transferring",
+			want: []int32{
+				packet.TitleActionSetDurations,
+				packet.TitleActionSetSubtitle,
+				packet.TitleActionSetTitle,
+				packet.TitleActionClear,
+				packet.TitleActionReset,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := *util.DefaultOpts()
+			opts.TransferTitle = tt.title
+			s, client := newTestSession(t, opts, tt.transport)
+			pks, _ := transferPackets(t, s, client, func() error { return s.Transfer("server") })
+
+			// The reposition of the player is recorded as -1, to check that the titles bracket the transfer.
+			var got []int32
+			for _, pk := range pks {
+				switch pk := pk.(type) {
+				case *packet.SetTitle:
+					got = append(got, pk.ActionType)
+				case *packet.MovePlayer:
+					got = append(got, -1)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("client received title actions %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// testProcessor is a processor calling its functions, if set, for every transfer about to be performed and every
+// transfer performed.
+type testProcessor struct {
+	NopProcessor
+	preTransfer  func(ctx *Context, origin, target *string)
+	postTransfer func(ctx *Context, origin, target *string)
+}
+
+// ProcessPreTransfer ...
+func (p testProcessor) ProcessPreTransfer(ctx *Context, origin, target *string) {
+	if p.preTransfer != nil {
+		p.preTransfer(ctx, origin, target)
+	}
+}
+
+// ProcessPostTransfer ...
+func (p testProcessor) ProcessPostTransfer(ctx *Context, origin, target *string) {
+	if p.postTransfer != nil {
+		p.postTransfer(ctx, origin, target)
+	}
+}
+
+func TestSessionTransferRejected(t *testing.T) {
+	tests := []struct {
+		name         string
+		preTransfer  func(ctx *Context, origin, target *string)
+		wantErr      string
+		wantMessages []string
+		wantDials    int32
+	}{
+		{name: "accepted", preTransfer: func(*Context, *string, *string) {}, wantDials: 1},
+		{name: "rejected", preTransfer: func(ctx *Context, _, _ *string) { ctx.Cancel() }, wantErr: "processor failed"},
+		{
+			name:         "rejected with message",
+			preTransfer:  func(ctx *Context, _, _ *string) { ctx.CancelWithMessage("That server is full") },
+			wantErr:      "processor failed: That server is full",
+			wantMessages: []string{"That server is full"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := testTransport{serve: testBackend(testServerGameData, nil), dials: &atomic.Int32{}}
+			s, client := newTestSession(t, *util.DefaultOpts(), transport)
+			s.SetProcessor(testProcessor{preTransfer: tt.preTransfer})
+			pks, err := transferPackets(t, s, client, func() error { return s.Transfer("server") })
+			if (err == nil && tt.wantErr != "") || (err != nil && err.Error() != tt.wantErr) {
+				t.Fatalf("Transfer() error = %v, want %q", err, tt.wantErr)
+			}
+			if dials := transport.dials.Load(); dials != tt.wantDials {
+				t.Fatalf("server dialed %v times, want %v", dials, tt.wantDials)
+			}
+
+			var messages []string
+			for _, text := range packetsOf[*packet.Text](pks) {
+				messages = append(messages, text.Message)
+			}
+			if !slices.Equal(messages, tt.wantMessages) {
+				t.Fatalf("client received messages %q, want %q", messages, tt.wantMessages)
+			}
+		})
+	}
+}
+
+func TestSessionSetOptsConcurrent(t *testing.T) {
+	opts := *util.DefaultOpts()
+	opts.LatencyInterval = 1
+	opts.TransferCooldown = 0
+	s, _ := newTestSession(t, opts, testTransport{serve: testBackend(testServerGameData, nil)})
+	s.wg.Add(1)
+	go handleLatency(s)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := range 200 {
+			opts.LatencyInterval = int64(i%5 + 1)
+			opts.TransferMoveMode = byte(i % 2)
+			s.SetOpts(opts)
+			_ = s.Opts()
+		}
+	}()
+	for _, addr := range []string{"first", "second", "third"} {
+		if err := s.Transfer(addr); err != nil {
+			t.Fatalf("Transfer() error = %v", err)
+		}
+		if err := s.waitTransfer(); err != nil {
+			t.Fatalf("session closed: %v", err)
+		}
+	}
+	<-done
+}
+
+func TestSessionResetGameDataWeather(t *testing.T) {
+	tests := []struct {
+		name      string
+		dimension int32
+		want      []int32
+	}{
+		{name: "overworld", dimension: packet.DimensionOverworld, want: []int32{packet.LevelEventStopRaining, packet.LevelEventStopThunderstorm}},
+		{name: "nether", dimension: packet.DimensionNether},
+		{name: "end", dimension: packet.DimensionEnd},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gameData := testServerGameData
+			gameData.Dimension = tt.dimension
+			s, client := newTestSession(t, *util.DefaultOpts(), testTransport{serve: testBackend(gameData, nil)})
+			pks, err := transferPackets(t, s, client, func() error { return s.Transfer("server") })
+			if err != nil {
+				t.Fatalf("Transfer() error = %v", err)
+			}
+
+			var got []int32
+			for _, event := range packetsOf[*packet.LevelEvent](pks) {
+				got = append(got, event.EventType)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("client received level events %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSessionMergeMetadata(t *testing.T) {
+	tests := []struct {
+		name      string
+		state     TransferState
+		runtimeID uint64
+		wantNoAI  bool
+	}{
+		{name: "transferring", state: TransferStateResetting, runtimeID: testServerGameData.EntityRuntimeID, wantNoAI: true},
+		{name: "not transferring", state: TransferStateCompleted, runtimeID: testServerGameData.EntityRuntimeID},
+		{name: "other entity", state: TransferStateResetting, runtimeID: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestSession(t, *util.DefaultOpts(), testTransport{})
+			s.transferState.Store(int32(tt.state))
+
+			// The server's actor data clears the flags sent by the proxy.
+			metadata := gtprotocol.NewEntityMetadata()
+			metadata.SetFlag(gtprotocol.EntityDataKeyFlags, gtprotocol.EntityDataFlagBreathing)
+			pk := &packet.SetActorData{EntityRuntimeID: tt.runtimeID, EntityMetadata: metadata}
+			s.mergeMetadata(pk)
+			if noAI := gtprotocol.EntityMetadata(pk.EntityMetadata).Flag(gtprotocol.EntityDataKeyFlags, gtprotocol.EntityDataFlagNoAI); noAI != tt.wantNoAI {
+				t.Fatalf("NoAI flag = %v, want %v", noAI, tt.wantNoAI)
+			}
+			if !gtprotocol.EntityMetadata(pk.EntityMetadata).Flag(gtprotocol.EntityDataKeyFlags, gtprotocol.EntityDataFlagBreathing) {
+				t.Fatal("mergeMetadata() cleared a flag set by the server")
+			}
+		})
+	}
+}
+
+func TestSessionReconnect(t *testing.T) {
+	tests := []struct {
+		name    string
+		drop    bool
+		wantErr bool
+	}{
+		{name: "connected", wantErr: true},
+		{name: "dropped", drop: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := *util.DefaultOpts()
+			opts.FallbackAttempts = -1
+			opts.LimboOnNoBackend = true
+			opts.LimboRetryInterval = 60_000
+			opts.TransferCooldown = 0
+			s, _ := newTestSession(t, opts, testTransport{serve: testBackend(testServerGameData, nil)})
+			s.discovery = testDiscovery{addr: "server"}
+			if err := s.Transfer("server"); err != nil {
+				t.Fatalf("Transfer() error = %v", err)
+			}
+			if err := s.waitTransfer(); err != nil {
+				t.Fatalf("session closed: %v", err)
+			}
+
+			conn := s.Server()
+			if tt.drop {
+				_ = conn.Close()
+				waitFor(t, func() bool { return s.Server() == nil })
+			}
+
+			if err := s.Reconnect(); (err != nil) != tt.wantErr {
+				t.Fatalf("Reconnect() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err := s.waitTransfer(); err != nil {
+				t.Fatalf("session closed: %v", err)
+			}
+			if reconnected := s.Server() != conn; reconnected != tt.drop {
+				t.Fatalf("session reconnected = %v, want %v", reconnected, tt.drop)
+			}
+			if s.TransferState() != TransferStateCompleted {
+				t.Fatalf("TransferState() = %v, want %v", s.TransferState(), TransferStateCompleted)
+			}
+		})
+	}
+}
+
+func TestSessionLoginGameData(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(gameData *minecraft.GameData)
+		wantKey string
+	}{
+		{name: "valid", modify: func(*minecraft.GameData) {}},
+		{name: "missing entity runtime id", modify: func(gameData *minecraft.GameData) {
+			gameData.EntityRuntimeID = 0
+		}, wantKey: util.MessageInvalidGameData},
+		{name: "unknown dimension", modify: func(gameData *minecraft.GameData) {
+			gameData.Dimension = 10
+		}, wantKey: util.MessageInvalidGameData},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gameData := testServerGameData
+			tt.modify(&gameData)
+			transport := testTransport{serve: testBackend(gameData, nil)}
+			s, client := newTestLoginSession(t, *util.DefaultOpts(), NewRegistry(), testDiscovery{addr: "server"}, transport)
+			err := s.Login()
+			if tt.wantKey == "" {
+				if err != nil {
+					t.Fatalf("Login() error = %v", err)
+				}
+				client.remote(t)
+				return
+			}
+
+			var e *messageError
+			if !errors.As(err, &e) || e.key != tt.wantKey {
+				t.Fatalf("Login() error = %v, want an error with message %q", err, tt.wantKey)
+			}
+		})
+	}
+}
+
+// testAnimation is an animation recording the calls of its methods.
+type testAnimation struct {
+	calls []string
+	mu    sync.Mutex
+}
+
+func (a *testAnimation) record(call string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.calls = append(a.calls, call)
+}
+
+// Play ...
+func (a *testAnimation) Play(*minecraft.Conn, minecraft.GameData) {
+	a.record("play")
+}
+
+// Clear ...
+func (a *testAnimation) Clear(*minecraft.Conn, minecraft.GameData) {
+	a.record("clear")
+}
+
+// Spawned ...
+func (a *testAnimation) Spawned(*minecraft.Conn, minecraft.GameData) {
+	a.record("spawned")
+}
+
+// Abort ...
+func (a *testAnimation) Abort(*minecraft.Conn) {
+	a.record("abort")
+}
+
+func TestSessionAnimationEnabled(t *testing.T) {
+	tests := []struct {
+		name    string
+		enabled bool
+		want    []string
+	}{
+		{name: "enabled", enabled: true, want: []string{"play", "spawned", "clear"}},
+		{name: "disabled"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestSession(t, *util.DefaultOpts(), testTransport{serve: testBackend(testServerGameData, nil)})
+			anim := &testAnimation{}
+			s.SetAnimation(anim)
+			s.SetAnimationEnabled(tt.enabled)
+			if err := s.Transfer("server"); err != nil {
+				t.Fatalf("Transfer() error = %v", err)
+			}
+			if err := s.waitTransfer(); err != nil {
+				t.Fatalf("session closed: %v", err)
+			}
+
+			anim.mu.Lock()
+			defer anim.mu.Unlock()
+			if !slices.Equal(anim.calls, tt.want) {
+				t.Fatalf("animation calls %v, want %v", anim.calls, tt.want)
+			}
+		})
+	}
+}
+
+func TestSessionTransferChunkProvider(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider func(dimension, x, z int32) ([]byte, uint32)
+		want     func(pos gtprotocol.ChunkPos) ([]byte, uint32)
+	}{
+		{
+			name: "empty",
+			want: func(gtprotocol.ChunkPos) ([]byte, uint32) {
+				return emptyChunk(packet.DimensionOverworld), 1
+			},
+		},
+		{
+			name: "custom",
+			provider: func(dimension, x, z int32) ([]byte, uint32) {
+				return []byte{byte(dimension), byte(x), byte(z)}, 2
+			},
+			want: func(pos gtprotocol.ChunkPos) ([]byte, uint32) {
+				return []byte{byte(packet.DimensionOverworld), byte(pos.X()), byte(pos.Z())}, 2
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := *util.DefaultOpts()
+			opts.TransferChunkProvider = tt.provider
+			s, client := newTestSession(t, opts, testTransport{serve: testBackend(testServerGameData, nil)})
+			s.SetAnimationEnabled(false)
+			pks, err := transferPackets(t, s, client, func() error { return s.Transfer("server") })
+			if err != nil {
+				t.Fatalf("Transfer() error = %v", err)
+			}
+
+			chunks := packetsOf[*packet.LevelChunk](pks)
+			if len(chunks) != 81 {
+				t.Fatalf("client received %v chunks, want 81", len(chunks))
+			}
+			for _, chunk := range chunks {
+				payload, subChunkCount := tt.want(chunk.Position)
+				if !bytes.Equal(chunk.RawPayload, payload) || chunk.SubChunkCount != subChunkCount {
+					t.Fatalf("chunk %v = %x (%v sub chunks), want %x (%v sub chunks)", chunk.Position, chunk.RawPayload, chunk.SubChunkCount, payload, subChunkCount)
+				}
+			}
+		})
+	}
+}
+
+func TestSessionLoginFilter(t *testing.T) {
+	errBanned := errors.New("banned")
+	tests := []struct {
+		name      string
+		filter    func(conn *minecraft.Conn) error
+		wantErr   error
+		wantDials int32
+	}{
+		{name: "no filter", wantDials: 1},
+		{name: "allowed", filter: func(*minecraft.Conn) error { return nil }, wantDials: 1},
+		{name: "rejected", filter: func(*minecraft.Conn) error { return errBanned }, wantErr: errBanned},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := *util.DefaultOpts()
+			opts.LoginFilter = tt.filter
+			discovery := testDiscovery{addr: "server", discoveries: &atomic.Int32{}}
+			transport := testTransport{serve: testBackend(testServerGameData, nil), dials: &atomic.Int32{}}
+			s, _ := newTestLoginSession(t, opts, NewRegistry(), discovery, transport)
+			if err := s.Login(); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Login() error = %v, want %v", err, tt.wantErr)
+			}
+			if discoveries, dials := discovery.discoveries.Load(), transport.dials.Load(); discoveries != tt.wantDials || dials != tt.wantDials {
+				t.Fatalf("session discovered %v and dialed %v times, want %v", discoveries, dials, tt.wantDials)
+			}
+		})
+	}
+}
+
+func TestSessionMigrate(t *testing.T) {
+	tests := []struct {
+		name         string
+		serve        func(conn net.Conn)
+		wantErr      bool
+		wantMigrated bool
+	}{
+		{name: "migrated", serve: testBackend(testServerGameData, nil), wantMigrated: true},
+		{name: "dial failed", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := *util.DefaultOpts()
+			opts.FallbackAttempts = -1
+			opts.TransferCooldown = 0
+			s, _ := newTestSession(t, opts, testTransport{serve: testBackend(testServerGameData, nil)})
+			if err := s.Transfer("server"); err != nil {
+				t.Fatalf("Transfer() error = %v", err)
+			}
+			if err := s.waitTransfer(); err != nil {
+				t.Fatalf("session closed: %v", err)
+			}
+
+			conn, migrated := s.Server(), testTransport{serve: tt.serve, dials: &atomic.Int32{}}
+			if err := s.Migrate(migrated); (err != nil) != tt.wantErr {
+				t.Fatalf("Migrate() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err := s.waitTransfer(); err != nil {
+				t.Fatalf("session closed: %v", err)
+			}
+			if dials := migrated.dials.Load(); dials != 1 {
+				t.Fatalf("new transport dialed %v times, want 1", dials)
+			}
+			if got := s.Server() != conn; got != tt.wantMigrated {
+				t.Fatalf("session migrated = %v, want %v", got, tt.wantMigrated)
+			}
+
+			s.serverMu.RLock()
+			addr := s.serverAddr
+			s.serverMu.RUnlock()
+			if addr != "server" {
+				t.Fatalf("server address = %q, want %q", addr, "server")
+			}
+		})
+	}
+}
+
+func TestSessionDuplicateLogin(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     string
+		wantErr    bool
+		wantClosed bool
+	}{
+		{name: "default", wantClosed: true},
+		{name: "kick old", policy: util.DuplicateLoginPolicyKickOld, wantClosed: true},
+		{name: "reject new", policy: util.DuplicateLoginPolicyRejectNew, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := *util.DefaultOpts()
+			opts.DuplicateLoginPolicy = tt.policy
+			registry, transport := NewRegistry(), testTransport{serve: testBackend(testServerGameData, nil)}
+			old, _ := newTestLoginSession(t, opts, registry, testDiscovery{addr: "server"}, transport)
+			if err := old.Login(); err != nil {
+				t.Fatalf("Login() error = %v", err)
+			}
+
+			s, _ := newTestLoginSession(t, opts, registry, testDiscovery{addr: "server"}, transport)
+			if err := s.Login(); (err != nil) != tt.wantErr {
+				t.Fatalf("Login() error = %v, want error %v", err, tt.wantErr)
+			}
+			if closed := old.Context().Err() != nil; closed != tt.wantClosed {
+				t.Fatalf("old session closed = %v, want %v", closed, tt.wantClosed)
+			}
+
+			want := old
+			if tt.wantClosed {
+				want = s
+			}
+			xuid := s.client.IdentityData().XUID
+			if registry.Count() != 1 || registry.GetSession(xuid) != want {
+				t.Fatalf("registry holds %v sessions, want only the live one", registry.Count())
+			}
+		})
+	}
+}
+
+func TestSessionFlush(t *testing.T) {
+	tests := []struct {
+		name    string
+		closed  bool
+		wantErr bool
+	}{
+		{name: "open"},
+		{name: "closed", closed: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, client := newTestSession(t, *util.DefaultOpts(), testTransport{})
+			if tt.closed {
+				_ = client.conn.Close()
+			}
+
+			s.sendMessage("flushed")
+			if err := s.Flush(); (err != nil) != tt.wantErr {
+				t.Fatalf("Flush() error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.closed {
+				readUntil(t, client.remote(t), func(pk packet.Packet) bool {
+					text, ok := pk.(*packet.Text)
+					return ok && text.Message == "flushed"
+				})
+			}
+		})
+	}
+}
+
+func TestSessionTransferResetFunc(t *testing.T) {
+	var gameData minecraft.GameData
+	tests := []struct {
+		name        string
+		reset       TransferResetFunc
+		wantDefault bool
+	}{
+		{name: "default", wantDefault: true},
+		{name: "overridden", reset: func(s *Session, dst minecraft.GameData) {
+			gameData = dst
+			s.sendMessage("reset")
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gameData = minecraft.GameData{}
+			s, client := newTestSession(t, *util.DefaultOpts(), testTransport{serve: testBackend(testServerGameData, nil)})
+			s.SetTransferResetFunc(tt.reset)
+			pks, err := transferPackets(t, s, client, func() error { return s.Transfer("server") })
+			if err != nil {
+				t.Fatalf("Transfer() error = %v", err)
+			}
+
+			if len(packetsOf[*packet.LevelChunk](pks)) == 0 {
+				t.Fatal("no empty chunks were sent")
+			}
+			for _, id := range []uint32{packet.IDMovePlayer, packet.IDSetDifficulty, packet.IDSetPlayerGameType, packet.IDGameRulesChanged} {
+				sent := slices.ContainsFunc(pks, func(pk packet.Packet) bool { return pk.ID() == id })
+				if sent != tt.wantDefault {
+					t.Fatalf("packet %v sent = %v, want %v", id, sent, tt.wantDefault)
+				}
+			}
+			if tt.reset == nil {
+				return
+			}
+
+			reset := slices.ContainsFunc(packetsOf[*packet.Text](pks), func(pk *packet.Text) bool { return pk.Message == "reset" })
+			if !reset || gameData.PlayerPosition != testServerGameData.PlayerPosition {
+				t.Fatalf("reset func called = %v with position %v, want called with %v", reset, gameData.PlayerPosition, testServerGameData.PlayerPosition)
+			}
+		})
+	}
+}
+
+func TestSessionWait(t *testing.T) {
+	tests := []struct {
+		name  string
+		close func(s *Session, conn *minecraft.Conn)
+	}{
+		{name: "closed", close: func(s *Session, _ *minecraft.Conn) { _ = s.Close() }},
+		{name: "client disconnected", close: func(_ *Session, conn *minecraft.Conn) { _ = conn.Close() }},
+		{name: "server disconnected", close: func(s *Session, _ *minecraft.Conn) { _ = s.Server().Close() }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := *util.DefaultOpts()
+			opts.FallbackAttempts = -1
+			s, client := newTestLoginSession(t, opts, NewRegistry(), testDiscovery{addr: "server"}, testTransport{serve: testBackend(testServerGameData, nil)})
+			if err := s.Login(); err != nil {
+				t.Fatalf("Login() error = %v", err)
+			}
+			tt.close(s, client.remote(t))
+			done := make(chan struct{})
+			go func() {
+				s.Wait()
+				close(done)
+			}()
+			select {
+			case <-done:
+			case <-time.After(time.Second * 10):
+				t.Fatal("session goroutines did not exit")
+			}
+		})
+	}
+}
+
+func TestPlayerMetadata(t *testing.T) {
+	defaults := []uint8{gtprotocol.EntityDataFlagBreathing, gtprotocol.EntityDataFlagHasGravity}
+	tests := []struct {
+		name      string
+		noAI      bool
+		flags     []uint8
+		wantFlags []uint8
+	}{
+		{name: "default", wantFlags: defaults},
+		{name: "no ai", noAI: true, wantFlags: append([]uint8{gtprotocol.EntityDataFlagNoAI}, defaults...)},
+		{name: "custom", flags: []uint8{gtprotocol.EntityDataFlagInvisible}, wantFlags: append([]uint8{gtprotocol.EntityDataFlagInvisible}, defaults...)},
+		{name: "custom default", flags: []uint8{gtprotocol.EntityDataFlagHasGravity}, wantFlags: defaults},
+		{name: "custom repeated", flags: []uint8{gtprotocol.EntityDataFlagInvisible, gtprotocol.EntityDataFlagInvisible}, wantFlags: append([]uint8{gtprotocol.EntityDataFlagInvisible}, defaults...)},
+		{name: "second flag field", flags: []uint8{70}, wantFlags: append([]uint8{70}, defaults...)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metadata := playerMetadata(tt.noAI, tt.flags)
+			for _, flag := range []uint8{gtprotocol.EntityDataFlagNoAI, gtprotocol.EntityDataFlagBreathing, gtprotocol.EntityDataFlagHasGravity, gtprotocol.EntityDataFlagInvisible, 70} {
+				key, index := uint32(gtprotocol.EntityDataKeyFlags), flag
+				if flag >= 64 {
+					key, index = gtprotocol.EntityDataKeyFlagsTwo, flag-64
+				}
+				if set, want := metadata.Flag(key, index), slices.Contains(tt.wantFlags, flag); set != want {
+					t.Fatalf("flag %v set = %v, want %v", flag, set, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSessionTransferEntityDataFlags(t *testing.T) {
+	opts := *util.DefaultOpts()
+	opts.EntityDataFlags = []uint8{gtprotocol.EntityDataFlagInvisible}
+	s, client := newTestSession(t, opts, testTransport{serve: testBackend(testServerGameData, nil)})
+	pks, err := transferPackets(t, s, client, func() error { return s.Transfer("server") })
+	if err != nil {
+		t.Fatalf("Transfer() error = %v", err)
+	}
+
+	var sent bool
+	for _, pk := range packetsOf[*packet.SetActorData](pks) {
+		if pk.EntityRuntimeID != s.client.GameData().EntityRuntimeID {
+			continue
+		}
+		sent = true
+		if !gtprotocol.EntityMetadata(pk.EntityMetadata).Flag(gtprotocol.EntityDataKeyFlags, gtprotocol.EntityDataFlagInvisible) {
+			t.Fatal("actor data sent without the custom flag")
+		}
+	}
+	if !sent {
+		t.Fatal("no actor data was sent for the player")
+	}
+}
+
+func TestSessionScheduleTransfer(t *testing.T) {
+	tests := []struct {
+		name      string
+		processor func(schedule func(ctx *Context, origin, target *string)) Processor
+	}{
+		{name: "pre transfer", processor: func(schedule func(ctx *Context, origin, target *string)) Processor {
+			return testProcessor{preTransfer: schedule}
+		}},
+		{name: "post transfer", processor: func(schedule func(ctx *Context, origin, target *string)) Processor {
+			return testProcessor{postTransfer: schedule}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := *util.DefaultOpts()
+			opts.TransferCooldown = 0
+			s, _ := newTestSession(t, opts, testTransport{serve: testBackend(testServerGameData, nil)})
+			s.SetProcessor(tt.processor(func(_ *Context, _, target *string) {
+				if *target == "first" {
+					s.ScheduleTransfer("second")
+				}
+			}))
+			if err := s.Transfer("first"); err != nil {
+				t.Fatalf("Transfer() error = %v", err)
+			}
+
+			waitFor(t, func() bool {
+				s.serverMu.RLock()
+				defer s.serverMu.RUnlock()
+				return s.serverAddr == "second"
+			})
+			if err := s.waitTransfer(); err != nil {
+				t.Fatalf("session closed: %v", err)
+			}
+			if s.TransferState() != TransferStateCompleted {
+				t.Fatalf("TransferState() = %v, want %v", s.TransferState(), TransferStateCompleted)
+			}
+		})
+	}
+}
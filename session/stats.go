@@ -0,0 +1,147 @@
+package session
+
+import (
+	"math/bits"
+	"slices"
+	"sync"
+	"time"
+)
+
+// networkStatsWindow is the amount of latency probes over which networkStats computes the loss.
+const networkStatsWindow = 32
+
+// NetworkStats holds statistics about the connection of a session, computed from the latency probes
+// periodically sent to the server.
+type NetworkStats struct {
+	// RTT is the latest round-trip time measured by the server.
+	RTT time.Duration
+	// Jitter is the mean variation between consecutive latency samples of the session, as in LatencyStats.
+	Jitter time.Duration
+	// LossPct is the percentage of probes that were not answered by the server, between 0 and 100.
+	LossPct float64
+}
+
+// networkStats records whether the most recent latency probes of a session were answered, along with the latest
+// round-trip time. The variation of the latency is computed from the session's latencyHistory instead.
+type networkStats struct {
+	// lost holds a bit for each of the most recent probes, set if it was not answered, the lowest bit being the
+	// most recent probe.
+	lost    uint32
+	count   int
+	rtt     int64
+	pending bool
+	mu      sync.Mutex
+}
+
+// probe records that a probe was sent, marking the previous probe as lost if it was never answered.
+func (n *networkStats) probe() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.pending {
+		n.record(true)
+	}
+	n.pending = true
+}
+
+// answer records the latency measured in response to the pending probe.
+func (n *networkStats) answer(latency int64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.pending {
+		n.pending = false
+		n.rtt = latency
+		n.record(false)
+	}
+}
+
+// record records the result of a probe, discarding the oldest result once networkStatsWindow are recorded.
+func (n *networkStats) record(lost bool) {
+	n.lost <<= 1
+	if lost {
+		n.lost |= 1
+	}
+	n.count = min(n.count+1, networkStatsWindow)
+}
+
+// stats computes the NetworkStats of the recorded probes, using the jitter of the session's latencyHistory.
+func (n *networkStats) stats(jitter time.Duration) NetworkStats {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	stats := NetworkStats{RTT: time.Duration(n.rtt) * time.Millisecond, Jitter: jitter}
+	if n.count > 0 {
+		stats.LossPct = float64(bits.OnesCount32(n.lost)) / float64(n.count) * 100
+	}
+	return stats
+}
+
+// latencyHistorySize is the amount of latency samples kept by latencyHistory.
+const latencyHistorySize = 128
+
+// LatencyStats holds statistics about the most recent latency samples of a session, each sample being the total
+// latency of the session as returned by Session.Latency when it was measured.
+type LatencyStats struct {
+	// Samples is the amount of samples the statistics were computed from.
+	Samples int
+	// Min is the lowest latency sampled.
+	Min time.Duration
+	// Avg is the mean of the latency samples.
+	Avg time.Duration
+	// Max is the highest latency sampled.
+	Max time.Duration
+	// P99 is the 99th percentile of the latency samples.
+	P99 time.Duration
+	// Jitter is the mean variation between consecutive latency samples.
+	Jitter time.Duration
+}
+
+// latencyHistory records the most recent latency samples of a session in a ring buffer.
+type latencyHistory struct {
+	samples [latencyHistorySize]int64
+	next    int
+	count   int
+	mu      sync.Mutex
+}
+
+// record appends the latency in milliseconds to the ring buffer, overwriting the oldest sample once full.
+func (h *latencyHistory) record(latency int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.samples[h.next] = latency
+	h.next = (h.next + 1) % latencyHistorySize
+	h.count = min(h.count+1, latencyHistorySize)
+}
+
+// stats computes the LatencyStats of the recorded samples.
+func (h *latencyHistory) stats() LatencyStats {
+	h.mu.Lock()
+	samples := make([]int64, h.count)
+	for i := range samples {
+		samples[i] = h.samples[(h.next-h.count+i+latencyHistorySize)%latencyHistorySize]
+	}
+	h.mu.Unlock()
+	if len(samples) == 0 {
+		return LatencyStats{}
+	}
+
+	var sum, variation int64
+	for i, sample := range samples {
+		sum += sample
+		if i > 0 {
+			variation += max(sample-samples[i-1], samples[i-1]-sample)
+		}
+	}
+
+	stats := LatencyStats{
+		Samples: len(samples),
+		Avg:     time.Duration(sum) * time.Millisecond / time.Duration(len(samples)),
+	}
+	if len(samples) > 1 {
+		stats.Jitter = time.Duration(variation) * time.Millisecond / time.Duration(len(samples)-1)
+	}
+
+	slices.Sort(samples)
+	stats.Min = time.Duration(samples[0]) * time.Millisecond
+	stats.Max = time.Duration(samples[len(samples)-1]) * time.Millisecond
+	stats.P99 = time.Duration(samples[(len(samples)*99+99)/100-1]) * time.Millisecond
+	return stats
+}
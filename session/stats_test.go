@@ -0,0 +1,96 @@
+package session
+
+import (
+	"math"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestNetworkStats(t *testing.T) {
+	// Each probe is answered with its latency, or lost if it is negative.
+	tests := []struct {
+		name     string
+		probes   []int64
+		wantRTT  time.Duration
+		wantLoss float64
+	}{
+		{name: "no probes"},
+		{name: "answered", probes: []int64{10, 20, 30}, wantRTT: time.Millisecond * 30},
+		{name: "lost", probes: []int64{10, -1, 20}, wantRTT: time.Millisecond * 20, wantLoss: 100.0 / 3},
+		{name: "lost consecutively", probes: []int64{-1, -1, 10}, wantRTT: time.Millisecond * 10, wantLoss: 200.0 / 3},
+		{name: "pending", probes: []int64{10, -1}, wantRTT: time.Millisecond * 10},
+		{name: "half lost", probes: slices.Concat(slices.Repeat([]int64{-1}, 16), slices.Repeat([]int64{5}, 16)), wantRTT: time.Millisecond * 5, wantLoss: 50},
+		{name: "lost outside window", probes: slices.Concat(slices.Repeat([]int64{-1}, 40), slices.Repeat([]int64{5}, networkStatsWindow)), wantRTT: time.Millisecond * 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := &networkStats{}
+			for _, latency := range tt.probes {
+				n.probe()
+				if latency >= 0 {
+					n.answer(latency)
+				}
+			}
+
+			stats := n.stats(time.Millisecond * 3)
+			if stats.RTT != tt.wantRTT || stats.Jitter != time.Millisecond*3 || math.Abs(stats.LossPct-tt.wantLoss) > 0.01 {
+				t.Fatalf("stats() = %+v, want RTT %v, jitter %v and loss %.2f", stats, tt.wantRTT, time.Millisecond*3, tt.wantLoss)
+			}
+		})
+	}
+}
+
+func TestLatencyHistoryStats(t *testing.T) {
+	sequence := make([]int64, latencyHistorySize+2)
+	for i := range sequence {
+		sequence[i] = int64(i)
+	}
+	tests := []struct {
+		name    string
+		samples []int64
+		want    LatencyStats
+	}{
+		{name: "no samples"},
+		{
+			name:    "single",
+			samples: []int64{10},
+			want:    LatencyStats{Samples: 1, Min: time.Millisecond * 10, Avg: time.Millisecond * 10, Max: time.Millisecond * 10, P99: time.Millisecond * 10},
+		},
+		{
+			name:    "varying",
+			samples: []int64{10, 40, 20},
+			want: LatencyStats{
+				Samples: 3,
+				Min:     time.Millisecond * 10,
+				Avg:     time.Millisecond * 70 / 3,
+				Max:     time.Millisecond * 40,
+				P99:     time.Millisecond * 40,
+				Jitter:  time.Millisecond * 25,
+			},
+		},
+		{
+			name:    "overwritten",
+			samples: sequence,
+			want: LatencyStats{
+				Samples: latencyHistorySize,
+				Min:     time.Millisecond * 2,
+				Avg:     time.Millisecond * 131 / 2,
+				Max:     time.Millisecond * (latencyHistorySize + 1),
+				P99:     time.Millisecond * latencyHistorySize,
+				Jitter:  time.Millisecond,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &latencyHistory{}
+			for _, sample := range tt.samples {
+				h.record(sample)
+			}
+			if stats := h.stats(); stats != tt.want {
+				t.Fatalf("stats() = %+v, want %+v", stats, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package session
+
+import "sync"
+
+// Store is a concurrency-safe key/value store attached to a session, allowing processors, discoveries and
+// plugins to keep state about a session, such as their selected game mode, without maintaining maps keyed by XUID.
+// Values are kept for the lifetime of the session, including across transfers.
+type Store struct {
+	values map[string]any
+	mu     sync.RWMutex
+}
+
+// Load returns the value stored for the key, reporting whether it was present.
+func (s *Store) Load(key string) (value any, ok bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	value, ok = s.values[key]
+	return
+}
+
+// Store stores the value for the key, replacing any value previously stored for it.
+func (s *Store) Store(key string, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.values == nil {
+		s.values = make(map[string]any)
+	}
+	s.values[key] = value
+}
+
+// LoadOrStore returns the value stored for the key if present. Otherwise, it stores and returns the provided
+// value. The loaded result reports whether the value was loaded rather than stored.
+func (s *Store) LoadOrStore(key string, value any) (actual any, loaded bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if actual, loaded = s.values[key]; loaded {
+		return
+	}
+
+	if s.values == nil {
+		s.values = make(map[string]any)
+	}
+	s.values[key] = value
+	return value, false
+}
+
+// Delete deletes the value stored for the key.
+func (s *Store) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.values, key)
+}
+
+// Range calls the function for every key and value in the store until it returns false. The store must not be
+// modified by the function.
+func (s *Store) Range(fn func(key string, value any) bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for key, value := range s.values {
+		if !fn(key, value) {
+			return
+		}
+	}
+}
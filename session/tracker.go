@@ -1,32 +1,79 @@
 package session
 
 import (
+	"bytes"
+	"slices"
 	"sync"
 
+	"github.com/sandertv/gophertunnel/minecraft"
 	"github.com/sandertv/gophertunnel/minecraft/protocol"
 	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
 	"github.com/scylladb/go-set/b16set"
 	"github.com/scylladb/go-set/i32set"
 	"github.com/scylladb/go-set/i64set"
 	"github.com/scylladb/go-set/strset"
+	"github.com/scylladb/go-set/u32set"
 )
 
+// rawTrackedPackets holds the IDs of the packets decoded by the tracker when a server forwards them without
+// requiring the proxy to decode them, as they are needed to reset the client's state during transfers.
+var rawTrackedPackets = []uint32{
+	packet.IDCameraInstruction,
+	packet.IDContainerClose,
+	packet.IDContainerOpen,
+	packet.IDInventoryContent,
+	packet.IDInventorySlot,
+	packet.IDModalFormRequest,
+	packet.IDNPCDialogue,
+	packet.IDPlayerFog,
+	packet.IDPlayerList,
+	packet.IDRemoveObjective,
+	packet.IDSetDisplayObjective,
+	packet.IDSetScore,
+}
+
+// scoreEntry identifies a score of a scoreboard objective.
+type scoreEntry struct {
+	objective string
+	id        int64
+}
+
 type tracker struct {
-	bossBars    *i64set.Set
-	effects     *i32set.Set
-	entities    *i64set.Set
-	players     *b16set.Set
-	scoreboards *strset.Set
-	mu          sync.Mutex
+	bossBars     *i64set.Set
+	camera       bool
+	cameraTarget bool
+	commands     bool
+	dialogues    map[uint64]string
+	displaySlots map[string]string
+	effects      *i32set.Set
+	entities     *i64set.Set
+	fog          bool
+	forms        *u32set.Set
+	inventories  map[uint32]int
+	links        map[int64]int64
+	players      *b16set.Set
+	scoreboards  *strset.Set
+	scores       map[scoreEntry]struct{}
+	staleForms   *u32set.Set
+	windows      map[byte]byte
+	mu           sync.Mutex
 }
 
 func newTracker() *tracker {
 	return &tracker{
-		bossBars:    i64set.New(),
-		effects:     i32set.New(),
-		entities:    i64set.New(),
-		players:     b16set.New(),
-		scoreboards: strset.New(),
+		bossBars:     i64set.New(),
+		dialogues:    make(map[uint64]string),
+		displaySlots: make(map[string]string),
+		effects:      i32set.New(),
+		entities:     i64set.New(),
+		forms:        u32set.New(),
+		inventories:  make(map[uint32]int),
+		links:        make(map[int64]int64),
+		players:      b16set.New(),
+		scoreboards:  strset.New(),
+		scores:       make(map[scoreEntry]struct{}),
+		staleForms:   u32set.New(),
+		windows:      make(map[byte]byte),
 	}
 }
 
@@ -34,22 +81,60 @@ func (t *tracker) handlePacket(pk packet.Packet) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 	switch pk := pk.(type) {
+	case *packet.AvailableCommands:
+		t.commands = true
+	case *packet.CameraInstruction:
+		if _, ok := pk.Set.Value(); ok {
+			t.camera = true
+		} else if cleared, ok := pk.Clear.Value(); ok && cleared {
+			t.camera = false
+		}
+		if _, ok := pk.Target.Value(); ok {
+			t.cameraTarget = true
+		} else if remove, ok := pk.RemoveTarget.Value(); ok && remove {
+			t.cameraTarget = false
+		}
 	case *packet.AddActor:
 		t.entities.Add(pk.EntityUniqueID)
+		for _, link := range pk.EntityLinks {
+			t.handleLink(link)
+		}
 	case *packet.AddItemActor:
 		t.entities.Add(pk.EntityUniqueID)
 	case *packet.AddPainting:
 		t.entities.Add(pk.EntityUniqueID)
 	case *packet.AddPlayer:
 		t.entities.Add(pk.AbilityData.EntityUniqueID)
+		for _, link := range pk.EntityLinks {
+			t.handleLink(link)
+		}
 	case *packet.BossEvent:
 		t.bossBars.Add(pk.BossEntityUniqueID)
+	case *packet.ContainerClose:
+		delete(t.windows, pk.WindowID)
+	case *packet.ContainerOpen:
+		t.windows[pk.WindowID] = pk.ContainerType
+	case *packet.InventoryContent:
+		t.inventories[pk.WindowID] = max(t.inventories[pk.WindowID], len(pk.Content))
+	case *packet.InventorySlot:
+		t.inventories[pk.WindowID] = max(t.inventories[pk.WindowID], int(pk.Slot)+1)
+	case *packet.ModalFormRequest:
+		t.forms.Add(pk.FormID)
+		t.staleForms.Remove(pk.FormID)
+	case *packet.NPCDialogue:
+		if pk.ActionType == packet.NPCDialogueActionOpen {
+			t.dialogues[pk.EntityUniqueID] = pk.SceneName
+		} else {
+			delete(t.dialogues, pk.EntityUniqueID)
+		}
 	case *packet.MobEffect:
 		if pk.Operation == packet.MobEffectAdd {
 			t.effects.Add(pk.EffectType)
 		} else if pk.Operation == packet.MobEffectRemove {
 			t.effects.Remove(pk.EffectType)
 		}
+	case *packet.PlayerFog:
+		t.fog = len(pk.Stack) > 0
 	case *packet.PlayerList:
 		for _, entry := range pk.Entries {
 			if pk.ActionType == packet.PlayerListActionAdd {
@@ -60,10 +145,104 @@ func (t *tracker) handlePacket(pk packet.Packet) {
 		}
 	case *packet.RemoveActor:
 		t.entities.Remove(pk.EntityUniqueID)
+		for rider, ridden := range t.links {
+			if rider == pk.EntityUniqueID || ridden == pk.EntityUniqueID {
+				delete(t.links, rider)
+			}
+		}
 	case *packet.RemoveObjective:
 		t.scoreboards.Remove(pk.ObjectiveName)
+		for slot, objective := range t.displaySlots {
+			if objective == pk.ObjectiveName {
+				delete(t.displaySlots, slot)
+			}
+		}
+		for entry := range t.scores {
+			if entry.objective == pk.ObjectiveName {
+				delete(t.scores, entry)
+			}
+		}
+	case *packet.SetActorLink:
+		t.handleLink(pk.EntityLink)
 	case *packet.SetDisplayObjective:
 		t.scoreboards.Add(pk.ObjectiveName)
+		t.displaySlots[pk.DisplaySlot] = pk.ObjectiveName
+	case *packet.SetScore:
+		for _, entry := range pk.Entries {
+			if pk.ActionType == packet.ScoreboardActionModify {
+				t.scores[scoreEntry{objective: entry.ObjectiveName, id: entry.EntryID}] = struct{}{}
+			} else {
+				delete(t.scores, scoreEntry{objective: entry.ObjectiveName, id: entry.EntryID})
+			}
+		}
+	}
+}
+
+// handleRaw decodes and handles a packet forwarded by the server without being decoded, if its ID is listed in
+// rawTrackedPackets. The payload includes its packet.Header.
+func (t *tracker) handleRaw(s *Session, shieldID int32, payload []byte) {
+	buf := bytes.NewBuffer(payload)
+	header := &packet.Header{}
+	if err := header.Read(buf); err != nil {
+		return
+	}
+
+	if header.PacketID == packet.IDAvailableCommands {
+		// Only whether the server sent commands is tracked, which does not require decoding the packet.
+		t.mu.Lock()
+		t.commands = true
+		t.mu.Unlock()
+		return
+	}
+
+	if !slices.Contains(rawTrackedPackets, header.PacketID) {
+		return
+	}
+
+	var proto minecraft.Protocol = minecraft.DefaultProtocol
+	syncProtocol := s.opts.Load().SyncProtocol
+	if syncProtocol {
+		proto = s.client.Proto()
+	}
+
+	factory, ok := proto.Packets(false)[header.PacketID]
+	if !ok {
+		return
+	}
+
+	defer func() {
+		// The packet is forwarded to the client regardless, a malformed packet only leaves it untracked.
+		_ = recover()
+	}()
+	pk := factory()
+	pk.Marshal(proto.NewReader(buf, shieldID, false))
+	if !syncProtocol {
+		t.handlePacket(pk)
+		return
+	}
+
+	for _, latest := range proto.ConvertToLatest(pk, s.client) {
+		t.handlePacket(latest)
+	}
+}
+
+// answerForm records the client's response to the form with the ID, reporting false if the form was closed when
+// the session was transferred away from the server that sent it, in which case the response must be dropped.
+func (t *tracker) answerForm(formID uint32) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.forms.Has(formID) {
+		t.forms.Remove(formID)
+		return true
+	}
+	return !t.staleForms.Has(formID)
+}
+
+func (t *tracker) handleLink(link protocol.EntityLink) {
+	if link.Type == protocol.EntityLinkRemove {
+		delete(t.links, link.RiderEntityUniqueID)
+	} else {
+		t.links[link.RiderEntityUniqueID] = link.RiddenEntityUniqueID
 	}
 }
 
@@ -78,6 +257,29 @@ func (t *tracker) clearBossBars(s *Session) {
 	t.bossBars.Clear()
 }
 
+func (t *tracker) clearCamera(s *Session) {
+	if t.camera || t.cameraTarget {
+		instruction := &packet.CameraInstruction{}
+		if t.camera {
+			instruction.Clear = protocol.Option(true)
+		}
+		if t.cameraTarget {
+			instruction.RemoveTarget = protocol.Option(true)
+		}
+		_ = s.client.WritePacket(instruction)
+	}
+	t.camera, t.cameraTarget = false, false
+}
+
+func (t *tracker) clearCommands(s *Session) {
+	// Sending an empty command list replaces the commands, including their enums and soft enums, that the
+	// client suggests for auto-completion.
+	if t.commands {
+		_ = s.client.WritePacket(&packet.AvailableCommands{})
+	}
+	t.commands = false
+}
+
 func (t *tracker) clearEffects(s *Session) {
 	t.effects.Each(func(i int32) bool {
 		_ = s.client.WritePacket(&packet.MobEffect{
@@ -91,6 +293,29 @@ func (t *tracker) clearEffects(s *Session) {
 }
 
 func (t *tracker) clearEntities(s *Session) {
+	// Links are removed and riders are despawned before the entities they ride, otherwise the client may
+	// be left with riders that are stuck to an entity that no longer exists.
+	for rider, ridden := range t.links {
+		_ = s.client.WritePacket(&packet.SetActorLink{
+			EntityLink: protocol.EntityLink{
+				RiddenEntityUniqueID: ridden,
+				RiderEntityUniqueID:  rider,
+				Type:                 protocol.EntityLinkRemove,
+				Immediate:            true,
+			},
+		})
+	}
+
+	for rider := range t.links {
+		if t.entities.Has(rider) {
+			_ = s.client.WritePacket(&packet.RemoveActor{
+				EntityUniqueID: rider,
+			})
+			t.entities.Remove(rider)
+		}
+	}
+	clear(t.links)
+
 	t.entities.Each(func(i int64) bool {
 		_ = s.client.WritePacket(&packet.RemoveActor{
 			EntityUniqueID: i,
@@ -100,6 +325,51 @@ func (t *tracker) clearEntities(s *Session) {
 	t.entities.Clear()
 }
 
+func (t *tracker) clearFog(s *Session) {
+	if t.fog {
+		_ = s.client.WritePacket(&packet.PlayerFog{})
+	}
+	t.fog = false
+}
+
+func (t *tracker) clearForms(s *Session) {
+	if !t.forms.IsEmpty() {
+		_ = s.client.WritePacket(&packet.ClientBoundCloseForm{})
+	}
+	t.staleForms = t.forms
+	t.forms = u32set.New()
+
+	for entityUniqueID, sceneName := range t.dialogues {
+		_ = s.client.WritePacket(&packet.NPCDialogue{
+			EntityUniqueID: entityUniqueID,
+			ActionType:     packet.NPCDialogueActionClose,
+			SceneName:      sceneName,
+		})
+	}
+	clear(t.dialogues)
+}
+
+func (t *tracker) clearInventories(s *Session) {
+	// Open containers are closed before the inventories are emptied, so that the client does not keep
+	// displaying a container of the previous server.
+	for windowID, containerType := range t.windows {
+		_ = s.client.WritePacket(&packet.ContainerClose{
+			WindowID:      windowID,
+			ContainerType: containerType,
+			ServerSide:    true,
+		})
+	}
+	clear(t.windows)
+
+	for windowID, size := range t.inventories {
+		_ = s.client.WritePacket(&packet.InventoryContent{
+			WindowID: windowID,
+			Content:  make([]protocol.ItemInstance, size),
+		})
+	}
+	clear(t.inventories)
+}
+
 func (t *tracker) clearPlayers(s *Session) {
 	entries := make([]protocol.PlayerListEntry, 0)
 	t.players.Each(func(i [16]byte) bool {
@@ -109,6 +379,9 @@ func (t *tracker) clearPlayers(s *Session) {
 		return true
 	})
 	t.players.Clear()
+	if len(entries) == 0 {
+		return
+	}
 
 	_ = s.client.WritePacket(&packet.PlayerList{
 		ActionType: packet.PlayerListActionRemove,
@@ -117,6 +390,33 @@ func (t *tracker) clearPlayers(s *Session) {
 }
 
 func (t *tracker) clearScoreboards(s *Session) {
+	// Scores are removed before their objectives, and the objective of every display slot is removed before
+	// the remaining ones, so that the new server can display its own objectives in any slot.
+	if len(t.scores) > 0 {
+		entries := make([]protocol.ScoreboardEntry, 0, len(t.scores))
+		for entry := range t.scores {
+			entries = append(entries, protocol.ScoreboardEntry{
+				EntryID:       entry.id,
+				ObjectiveName: entry.objective,
+			})
+		}
+		_ = s.client.WritePacket(&packet.SetScore{
+			ActionType: packet.ScoreboardActionRemove,
+			Entries:    entries,
+		})
+	}
+	clear(t.scores)
+
+	for _, objective := range t.displaySlots {
+		if t.scoreboards.Has(objective) {
+			_ = s.client.WritePacket(&packet.RemoveObjective{
+				ObjectiveName: objective,
+			})
+			t.scoreboards.Remove(objective)
+		}
+	}
+	clear(t.displaySlots)
+
 	t.scoreboards.Each(func(i string) bool {
 		_ = s.client.WritePacket(&packet.RemoveObjective{
 			ObjectiveName: i,
@@ -0,0 +1,138 @@
+package session
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/cooldogedev/spectrum/util"
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+)
+
+func TestTrackerClearEntities(t *testing.T) {
+	link := protocol.EntityLink{RiddenEntityUniqueID: 10, RiderEntityUniqueID: 11, Type: protocol.EntityLinkRider}
+	tests := []struct {
+		name string
+		pks  []packet.Packet
+		want []string
+	}{
+		{name: "no entities"},
+		{name: "single entity", pks: []packet.Packet{&packet.AddActor{EntityUniqueID: 10}}, want: []string{"remove 10"}},
+		{
+			name: "linked rider",
+			pks: []packet.Packet{
+				&packet.AddActor{EntityUniqueID: 10},
+				&packet.AddActor{EntityUniqueID: 11},
+				&packet.SetActorLink{EntityLink: link},
+			},
+			want: []string{"unlink 11 from 10", "remove 11", "remove 10"},
+		},
+		{
+			name: "rider spawned riding",
+			pks: []packet.Packet{
+				&packet.AddActor{EntityUniqueID: 11},
+				&packet.AddActor{EntityUniqueID: 10, EntityLinks: []protocol.EntityLink{link}},
+			},
+			want: []string{"unlink 11 from 10", "remove 11", "remove 10"},
+		},
+		{
+			name: "vehicle removed",
+			pks: []packet.Packet{
+				&packet.AddActor{EntityUniqueID: 10},
+				&packet.AddActor{EntityUniqueID: 11},
+				&packet.SetActorLink{EntityLink: link},
+				&packet.RemoveActor{EntityUniqueID: 10},
+			},
+			want: []string{"remove 11"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, client := newTestSession(t, *util.DefaultOpts(), testTransport{})
+			for _, pk := range tt.pks {
+				s.tracker.handlePacket(pk)
+			}
+			pks, _ := transferPackets(t, s, client, func() error {
+				s.tracker.mu.Lock()
+				defer s.tracker.mu.Unlock()
+				s.tracker.clearEntities(s)
+				return nil
+			})
+
+			var got []string
+			for _, pk := range pks {
+				switch pk := pk.(type) {
+				case *packet.SetActorLink:
+					if pk.EntityLink.Type == protocol.EntityLinkRemove {
+						got = append(got, fmt.Sprintf("unlink %v from %v", pk.EntityLink.RiderEntityUniqueID, pk.EntityLink.RiddenEntityUniqueID))
+					}
+				case *packet.RemoveActor:
+					got = append(got, fmt.Sprintf("remove %v", pk.EntityUniqueID))
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("clearEntities() sent %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSessionTransferWithOptionsKeep(t *testing.T) {
+	tracked := []packet.Packet{
+		&packet.AddActor{EntityUniqueID: 10},
+		&packet.MobEffect{EntityRuntimeID: 1, Operation: packet.MobEffectAdd, EffectType: packet.EffectSpeed},
+		&packet.BossEvent{BossEntityUniqueID: 20, EventType: packet.BossEventShow},
+		&packet.PlayerList{ActionType: packet.PlayerListActionAdd, Entries: []protocol.PlayerListEntry{{UUID: [16]byte{1}}}},
+		&packet.SetDisplayObjective{DisplaySlot: "sidebar", ObjectiveName: "objective"},
+	}
+	all := []string{"effects", "entities", "boss bars", "players", "scoreboards"}
+	tests := []struct {
+		name        string
+		opts        TransferOptions
+		wantCleared []string
+	}{
+		{name: "default", wantCleared: all},
+		{name: "keep entities", opts: TransferOptions{KeepEntities: true}, wantCleared: []string{"effects", "boss bars", "players", "scoreboards"}},
+		{name: "keep effects", opts: TransferOptions{KeepEffects: true}, wantCleared: []string{"entities", "boss bars", "players", "scoreboards"}},
+		{name: "keep shared state", opts: TransferOptions{KeepBossBars: true, KeepScoreboards: true}, wantCleared: []string{"effects", "entities", "players"}},
+		{name: "keep everything", opts: TransferOptions{KeepEntities: true, KeepEffects: true, KeepBossBars: true, KeepPlayers: true, KeepScoreboards: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, client := newTestSession(t, *util.DefaultOpts(), testTransport{serve: testBackend(testServerGameData, nil)})
+			for _, pk := range tracked {
+				s.tracker.handlePacket(pk)
+			}
+			pks, err := transferPackets(t, s, client, func() error { return s.TransferWithOptions("server", tt.opts) })
+			if err != nil {
+				t.Fatalf("TransferWithOptions() error = %v", err)
+			}
+
+			var cleared []string
+			for _, pk := range pks {
+				switch pk := pk.(type) {
+				case *packet.RemoveActor:
+					cleared = append(cleared, "entities")
+				case *packet.MobEffect:
+					if pk.Operation == packet.MobEffectRemove {
+						cleared = append(cleared, "effects")
+					}
+				case *packet.BossEvent:
+					if pk.EventType == packet.BossEventHide {
+						cleared = append(cleared, "boss bars")
+					}
+				case *packet.PlayerList:
+					if pk.ActionType == packet.PlayerListActionRemove {
+						cleared = append(cleared, "players")
+					}
+				case *packet.RemoveObjective:
+					cleared = append(cleared, "scoreboards")
+				}
+			}
+			if !slices.Equal(cleared, tt.wantCleared) {
+				t.Fatalf("TransferWithOptions() cleared %v, want %v", cleared, tt.wantCleared)
+			}
+		})
+	}
+}
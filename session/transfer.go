@@ -0,0 +1,267 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/sandertv/gophertunnel/minecraft"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+)
+
+// ErrTransferLoop is returned when a transfer is refused because the target server has been visited
+// too many times within a short period of time, which usually indicates a misconfigured discovery or
+// servers repeatedly transferring the player between each other.
+var ErrTransferLoop = errors.New("transfer loop detected")
+
+// ErrTransferCooldown is returned when a transfer is refused because the session was transferred less than
+// util.Opts.TransferCooldown ago. Transfers initiated by the proxy itself, such as fallbacks, are not refused.
+var ErrTransferCooldown = errors.New("transfer cooldown")
+
+// ErrTransferTimeout is reported when a transfer did not complete before its deadline, in which case the
+// connection to the target server is closed.
+var ErrTransferTimeout = errors.New("transfer timed out")
+
+// defaultTransferTimeout is the maximum duration of transfers initiated with a context that has no deadline.
+const defaultTransferTimeout = time.Minute
+
+// DialError is returned when dialing a server failed after all the attempts allowed by util.Opts.DialAttempts.
+type DialError struct {
+	// Addr is the address of the server that was dialed.
+	Addr string
+	// Attempts is the amount of dials attempted.
+	Attempts int
+	// Err is the error returned by the last attempt.
+	Err error
+}
+
+// Error ...
+func (e *DialError) Error() string {
+	return fmt.Sprintf("failed to dial %v after %d attempts: %v", e.Addr, e.Attempts, e.Err)
+}
+
+// Unwrap ...
+func (e *DialError) Unwrap() error {
+	return e.Err
+}
+
+// transferHistorySize is the maximum amount of transfers recorded by a transferHistory.
+const transferHistorySize = 64
+
+// ConnectionTiming holds the time taken by each stage of establishing a connection to a server, during either
+// the login sequence or a transfer.
+type ConnectionTiming struct {
+	// Dial is the time taken to dial the server.
+	Dial time.Duration
+	// Connect is the time taken by the connection sequence, from sending the connection request until the
+	// server finished sending its game data.
+	Connect time.Duration
+	// Spawn is the time taken to start the game for the player, or to reset it in case of a transfer, and to
+	// spawn it in the server.
+	Spawn time.Duration
+}
+
+// TransferResetFunc is a function resetting the player's state to the provided game data of the server the
+// session is being transferred to. It is set using Session.SetTransferResetFunc.
+type TransferResetFunc func(s *Session, gameData minecraft.GameData)
+
+// TransferState represents the progress of a session's transfer.
+type TransferState int32
+
+const (
+	// TransferStateIdle indicates that the session has not been transferred yet.
+	TransferStateIdle TransferState = iota
+	// TransferStateDialing indicates that the target server is being dialed.
+	TransferStateDialing
+	// TransferStateConnecting indicates that the connection sequence with the target server is in progress.
+	TransferStateConnecting
+	// TransferStateResetting indicates that the client's state is being reset for the target server.
+	TransferStateResetting
+	// TransferStateSpawning indicates that the player is being spawned in the target server.
+	TransferStateSpawning
+	// TransferStateCompleted indicates that the last transfer has completed.
+	TransferStateCompleted
+	// TransferStateFailed indicates that the last transfer has failed.
+	TransferStateFailed
+)
+
+// String ...
+func (state TransferState) String() string {
+	switch state {
+	case TransferStateIdle:
+		return "idle"
+	case TransferStateDialing:
+		return "dialing"
+	case TransferStateConnecting:
+		return "connecting"
+	case TransferStateResetting:
+		return "resetting"
+	case TransferStateSpawning:
+		return "spawning"
+	case TransferStateCompleted:
+		return "completed"
+	case TransferStateFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+// InProgress reports whether the state belongs to a transfer that has neither completed nor failed.
+func (state TransferState) InProgress() bool {
+	return state != TransferStateIdle && state != TransferStateCompleted && state != TransferStateFailed
+}
+
+// TransferOptions holds the options of a transfer, such as the categories of tracked state kept when a session
+// is transferred. The zero value clears all tracked state, like Session.Transfer does.
+type TransferOptions struct {
+	// KeepCamera determines whether the camera set by the previous server is kept rather than reset, which is
+	// otherwise done before the transfer animation is played.
+	KeepCamera bool
+	// KeepCommands determines whether the commands sent by the previous server are kept in the client's
+	// auto-completion.
+	KeepCommands bool
+	// KeepEntities determines whether the entities spawned by the previous server are kept.
+	KeepEntities bool
+	// KeepEffects determines whether the effects applied by the previous server are kept.
+	KeepEffects bool
+	// KeepBossBars determines whether the boss bars shown by the previous server remain shown.
+	KeepBossBars bool
+	// KeepFog determines whether the fog stack applied by the previous server is kept.
+	KeepFog bool
+	// KeepForms determines whether the forms and NPC dialogues opened by the previous server remain open. Once they
+	// are closed, responses to them sent by the client afterwards are dropped rather than forwarded to the new server.
+	KeepForms bool
+	// KeepInventories determines whether the containers opened by the previous server remain open and the
+	// inventories filled by it are kept.
+	KeepInventories bool
+	// KeepPlayers determines whether the player list entries added by the previous server are kept.
+	KeepPlayers bool
+	// KeepScoreboards determines whether the scoreboards displayed by the previous server are kept.
+	KeepScoreboards bool
+
+	// Timeout is the maximum duration of the transfer, including the connection sequence and the spawn of the
+	// player. A timeout of 0 uses the default timeout of the transfer method used.
+	Timeout time.Duration
+	// Fallback determines whether the session is transferred to a fallback server if the target server could not
+	// be dialed. The player otherwise remains on their current server.
+	Fallback bool
+	// OnComplete, if set, is called once the transfer has either completed or failed, with the error it failed
+	// with. It is called exactly once, including when the transfer is refused before it starts.
+	OnComplete func(err error)
+}
+
+// DefaultTransferOptions returns the TransferOptions used by Session.Transfer, clearing all tracked state. It is
+// equal to the zero value of TransferOptions.
+func DefaultTransferOptions() TransferOptions {
+	return TransferOptions{}
+}
+
+// transferRequest holds the parameters of a single transfer.
+type transferRequest struct {
+	// options overrides the tracked state cleared during the transfer, if set.
+	options *TransferOptions
+	// position overrides the position the player is moved to once the transfer completes, if set.
+	position *transferPosition
+	// force determines whether the transfer cooldown is ignored.
+	force bool
+	// group is the name of the server group the target server was discovered from, if any.
+	group string
+	// onState is called every time the state of the transfer changes, if set.
+	onState func(state TransferState)
+}
+
+// transferOptions returns the options of the transfer, or DefaultTransferOptions if it has none.
+func (req transferRequest) transferOptions() TransferOptions {
+	if req.options != nil {
+		return *req.options
+	}
+	return DefaultTransferOptions()
+}
+
+// complete calls the completion hook of the transfer's options, if any.
+func (req transferRequest) complete(err error) {
+	if req.options != nil && req.options.OnComplete != nil {
+		req.options.OnComplete(err)
+	}
+}
+
+// transferPosition is a position and rotation the player is moved to after a transfer.
+type transferPosition struct {
+	pos   mgl32.Vec3
+	yaw   float32
+	pitch float32
+}
+
+// finite reports whether all components of the position and rotation are finite numbers.
+func (p *transferPosition) finite() bool {
+	for _, f := range []float32{p.pos.X(), p.pos.Y(), p.pos.Z(), p.yaw, p.pitch} {
+		if math.IsNaN(float64(f)) || math.IsInf(float64(f), 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// within reports whether the position is within the vertical bounds of the given dimension.
+func (p *transferPosition) within(dimension int32) bool {
+	minY, maxY := dimensionRange(dimension)
+	return p.pos.Y() >= minY && p.pos.Y() <= maxY
+}
+
+// dimensionRange returns the vertical bounds of the given dimension.
+func dimensionRange(dimension int32) (float32, float32) {
+	switch dimension {
+	case packet.DimensionNether:
+		return 0, 128
+	case packet.DimensionEnd:
+		return 0, 256
+	default:
+		return -64, 320
+	}
+}
+
+// transferHistory records the recent transfer targets of a session to detect transfer loops.
+type transferHistory struct {
+	entries []transferHistoryEntry
+	mu      sync.Mutex
+}
+
+// transferHistoryEntry is a single transfer recorded by a transferHistory.
+type transferHistoryEntry struct {
+	addr string
+	at   time.Time
+}
+
+// visit records a transfer to the given address, returning false without recording it if the address
+// has already been visited threshold times within the window.
+func (h *transferHistory) visit(addr string, window time.Duration, threshold int) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := time.Now()
+	entries := h.entries[:0]
+	var visits int
+	for _, entry := range h.entries {
+		if now.Sub(entry.at) > window {
+			continue
+		}
+
+		entries = append(entries, entry)
+		if entry.addr == addr {
+			visits++
+		}
+	}
+	h.entries = entries
+	if visits >= threshold {
+		return false
+	}
+
+	if len(h.entries) >= transferHistorySize {
+		h.entries = append(h.entries[:0], h.entries[1:]...)
+	}
+	h.entries = append(h.entries, transferHistoryEntry{addr: addr, at: now})
+	return true
+}
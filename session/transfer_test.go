@@ -0,0 +1,266 @@
+package session
+
+import (
+	"net"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cooldogedev/spectrum/util"
+	"github.com/sandertv/gophertunnel/minecraft"
+)
+
+func TestTransferHistoryVisit(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name      string
+		entries   []transferHistoryEntry
+		addr      string
+		threshold int
+		want      bool
+	}{
+		{name: "first visit", addr: "a", threshold: 2, want: true},
+		{
+			name:      "below threshold",
+			entries:   []transferHistoryEntry{{addr: "a", at: now}},
+			addr:      "a",
+			threshold: 2,
+			want:      true,
+		},
+		{
+			name:      "threshold reached",
+			entries:   []transferHistoryEntry{{addr: "a", at: now}, {addr: "b", at: now}, {addr: "a", at: now}},
+			addr:      "a",
+			threshold: 2,
+		},
+		{
+			name:      "other address",
+			entries:   []transferHistoryEntry{{addr: "a", at: now}, {addr: "a", at: now}},
+			addr:      "b",
+			threshold: 2,
+			want:      true,
+		},
+		{
+			name:      "visits outside the window",
+			entries:   []transferHistoryEntry{{addr: "a", at: now.Add(-time.Hour)}, {addr: "a", at: now.Add(-time.Hour)}},
+			addr:      "a",
+			threshold: 2,
+			want:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &transferHistory{entries: tt.entries}
+			if got := h.visit(tt.addr, time.Minute, tt.threshold); got != tt.want {
+				t.Fatalf("visit() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransferHistoryLoop(t *testing.T) {
+	h := &transferHistory{}
+	for i := range 4 {
+		for _, addr := range []string{"a", "b"} {
+			if !h.visit(addr, time.Minute, 4) {
+				t.Fatalf("visit(%q) refused transfer %v below the threshold", addr, i+1)
+			}
+		}
+	}
+	if h.visit("a", time.Minute, 4) {
+		t.Fatal("visit() allowed a transfer loop above the threshold")
+	}
+	if len(h.entries) != 8 {
+		t.Fatalf("visit() recorded %v entries, want 8", len(h.entries))
+	}
+}
+
+func TestTransferHistorySize(t *testing.T) {
+	h := &transferHistory{}
+	for range transferHistorySize * 2 {
+		h.visit("a", time.Minute, transferHistorySize*4)
+	}
+	if len(h.entries) != transferHistorySize {
+		t.Fatalf("history holds %v entries, want %v", len(h.entries), transferHistorySize)
+	}
+}
+
+func TestTransferWithProgress(t *testing.T) {
+	invalid := testServerGameData
+	invalid.EntityRuntimeID = 0
+	tests := []struct {
+		name     string
+		gameData *minecraft.GameData
+		visited  bool
+		want     []TransferState
+	}{
+		{
+			name:     "completed",
+			gameData: &testServerGameData,
+			want: []TransferState{
+				TransferStateDialing,
+				TransferStateConnecting,
+				TransferStateResetting,
+				TransferStateSpawning,
+				TransferStateCompleted,
+			},
+		},
+		{name: "dial failed", want: []TransferState{TransferStateDialing, TransferStateFailed}},
+		{
+			name:     "invalid game data",
+			gameData: &invalid,
+			want:     []TransferState{TransferStateDialing, TransferStateConnecting, TransferStateFailed},
+		},
+		{name: "transfer loop", gameData: &testServerGameData, visited: true, want: []TransferState{TransferStateFailed}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := *util.DefaultOpts()
+			opts.FallbackAttempts = -1
+			opts.TransferLoopThreshold = 1
+			opts.TransferLoopWindow = 60000
+
+			// The connection sequence is held back until the transfer reports connecting, so that it cannot
+			// move past that state before it is reported.
+			connecting := make(chan struct{})
+			var transport testTransport
+			if tt.gameData != nil {
+				transport.serve = testBackend(*tt.gameData, connecting)
+			}
+
+			s, _ := newTestSession(t, opts, transport)
+			if tt.visited {
+				s.transferHistory.visit("server", time.Minute, 1)
+			}
+
+			states := make(chan TransferState, 16)
+			_ = s.TransferWithProgress("server", func(state TransferState) {
+				if state == TransferStateConnecting {
+					close(connecting)
+				}
+				states <- state
+			})
+
+			var got []TransferState
+			for len(got) == 0 || got[len(got)-1].InProgress() {
+				select {
+				case state := <-states:
+					got = append(got, state)
+				case <-time.After(time.Second * 10):
+					t.Fatalf("transfer did not finish, reported states %v", got)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("reported states %v, want %v", got, tt.want)
+			}
+			if state := s.TransferState(); state != tt.want[len(tt.want)-1] {
+				t.Fatalf("TransferState() = %v, want %v", state, tt.want[len(tt.want)-1])
+			}
+		})
+	}
+}
+
+func TestSessionConnectionTiming(t *testing.T) {
+	const delay = time.Millisecond * 100
+	tests := []struct {
+		name  string
+		login bool
+	}{
+		{name: "login", login: true},
+		{name: "transfer"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The connection sequence of the server is delayed, so that it takes longer than dialing.
+			transport := testTransport{serve: func(conn net.Conn) {
+				start := make(chan struct{})
+				time.AfterFunc(delay, func() { close(start) })
+				testBackend(testServerGameData, start)(conn)
+			}}
+
+			var timing ConnectionTiming
+			if tt.login {
+				s, _ := newTestLoginSession(t, *util.DefaultOpts(), NewRegistry(), testDiscovery{addr: "server"}, transport)
+				if (s.LastLoginTiming() != ConnectionTiming{}) {
+					t.Fatal("login timing recorded before logging in")
+				}
+				if err := s.Login(); err != nil {
+					t.Fatalf("Login() error = %v", err)
+				}
+				timing = s.LastLoginTiming()
+			} else {
+				s, _ := newTestSession(t, *util.DefaultOpts(), transport)
+				if (s.LastTransferTiming() != ConnectionTiming{}) {
+					t.Fatal("transfer timing recorded before transferring")
+				}
+				if err := s.Transfer("server"); err != nil {
+					t.Fatalf("Transfer() error = %v", err)
+				}
+				if err := s.waitTransfer(); err != nil {
+					t.Fatalf("session closed: %v", err)
+				}
+				timing = s.LastTransferTiming()
+			}
+
+			if timing.Dial <= 0 || timing.Connect < delay || timing.Spawn <= 0 || timing.Dial >= timing.Connect {
+				t.Fatalf("timing = %+v, want a positive dial shorter than a connection sequence of at least %v and a positive spawn", timing, delay)
+			}
+		})
+	}
+}
+
+func TestTransferClientClosed(t *testing.T) {
+	tests := []struct {
+		name    string
+		closeOn TransferState
+		want    []TransferState
+	}{
+		{name: "before transfer", closeOn: TransferStateIdle},
+		{name: "connecting", closeOn: TransferStateConnecting, want: []TransferState{TransferStateDialing, TransferStateConnecting, TransferStateFailed}},
+		{name: "resetting", closeOn: TransferStateResetting, want: []TransferState{TransferStateDialing, TransferStateConnecting, TransferStateResetting, TransferStateFailed}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := *util.DefaultOpts()
+			opts.FallbackAttempts = -1
+			connecting := make(chan struct{})
+			transport := testTransport{serve: testBackend(testServerGameData, connecting), dials: &atomic.Int32{}}
+			s, client := newTestSession(t, opts, transport)
+			if tt.closeOn == TransferStateIdle {
+				_ = client.conn.Close()
+			}
+
+			states := make(chan TransferState, 16)
+			err := s.TransferWithProgress("server", func(state TransferState) {
+				if state == tt.closeOn {
+					_ = client.conn.Close()
+					<-client.conn.Context().Done()
+				}
+				if state == TransferStateConnecting {
+					close(connecting)
+				}
+				states <- state
+			})
+			if tt.want == nil {
+				if err == nil || transport.dials.Load() != 0 {
+					t.Fatalf("TransferWithProgress() error = %v after %v dials, want an error without dialing", err, transport.dials.Load())
+				}
+				return
+			}
+
+			var got []TransferState
+			for len(got) == 0 || got[len(got)-1].InProgress() {
+				select {
+				case state := <-states:
+					got = append(got, state)
+				case <-time.After(time.Second * 10):
+					t.Fatalf("transfer did not finish, reported states %v", got)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("reported states %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,26 +3,43 @@ package spectrum
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/cooldogedev/spectrum/server"
 	"github.com/cooldogedev/spectrum/session"
 	tr "github.com/cooldogedev/spectrum/transport"
 	"github.com/cooldogedev/spectrum/util"
 	"github.com/sandertv/gophertunnel/minecraft"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
 )
 
+// ErrShuttingDown is returned by Spectrum.Accept for connections accepted once Spectrum.Shutdown was called,
+// which are disconnected with util.Opts.ShutdownMessage.
+var ErrShuttingDown = errors.New("spectrum is shutting down")
+
 // Spectrum represents a proxy server managing server discovery,
 // network transport, and connections through an underlying minecraft.Listener.
 type Spectrum struct {
 	discovery server.Discovery
 	transport tr.Transport
 
-	listener *minecraft.Listener
-	registry *session.Registry
+	listener    *minecraft.Listener
+	registry    *session.Registry
+	throttle    *connThrottle
+	networkOnce sync.Once
+
+	logins   map[*session.Session]struct{}
+	loginsMu sync.Mutex
 
-	logger *slog.Logger
-	opts   util.Opts
+	logger       *slog.Logger
+	opts         util.Opts
+	shuttingDown atomic.Bool
 }
 
 // NewSpectrum creates a new Spectrum instance using the provided server.Discovery.
@@ -36,21 +53,34 @@ func NewSpectrum(discovery server.Discovery, logger *slog.Logger, opts *util.Opt
 	if transport == nil {
 		transport = tr.NewSpectral(logger)
 	}
-	return &Spectrum{
+	s := &Spectrum{
 		discovery: discovery,
 		transport: transport,
 
 		registry: session.NewRegistry(),
+		throttle: newConnThrottle(*opts),
+
+		logins: make(map[*session.Session]struct{}),
 
 		logger: logger,
 		opts:   *opts,
 	}
+	s.registry.OnAdd(s.finishLogin)
+	return s
 }
 
 // Listen sets up a minecraft.Listener for incoming connections based on the provided minecraft.ListenConfig.
 // The listener is then used by the Accept() method for accepting incoming connections.
 func (s *Spectrum) Listen(config minecraft.ListenConfig) (err error) {
-	listener, err := config.Listen("raknet", s.opts.Addr)
+	// Every Spectrum registers its own network, as the connections accepted are limited by its own throttle. It is
+	// only registered once, so that listening again does not register another network.
+	network := fmt.Sprintf("spectrum_raknet_%p", s)
+	s.networkOnce.Do(func() {
+		minecraft.RegisterNetwork(network, func(l *slog.Logger) minecraft.Network {
+			return throttledNetwork{logger: l, throttle: s.throttle}
+		})
+	})
+	listener, err := config.Listen(network, s.opts.Addr)
 	if err != nil {
 		s.logger.Error("failed to listen", "err", err)
 		return err
@@ -70,13 +100,26 @@ func (s *Spectrum) Accept() (*session.Session, error) {
 	}
 
 	conn := c.(*minecraft.Conn)
+	s.throttle.release(conn.RemoteAddr())
+	if s.shuttingDown.Load() {
+		_ = s.listener.Disconnect(conn, s.opts.ShutdownMessage)
+		return nil, ErrShuttingDown
+	}
+
 	identityData := conn.IdentityData()
 	logger := s.logger.With("username", identityData.DisplayName)
 	newSession := session.NewSession(conn, logger, s.registry, s.discovery, s.opts, s.transport)
+	// The login of the session is in flight until the session is added to the registry or closed.
+	s.loginsMu.Lock()
+	s.logins[newSession] = struct{}{}
+	s.loginsMu.Unlock()
+	context.AfterFunc(newSession.Context(), func() {
+		s.finishLogin(newSession)
+	})
 	if s.opts.AutoLogin {
 		go func() {
 			if err := newSession.Login(); err != nil {
-				newSession.Disconnect(err.Error())
+				newSession.CloseWithError(err)
 				if !errors.Is(err, context.Canceled) {
 					logger.Error("failed to login session", "err", err)
 				}
@@ -112,10 +155,92 @@ func (s *Spectrum) Transport() tr.Transport {
 	return s.transport
 }
 
+// BanIP refuses connections from the IP address until the duration has passed. Sessions already connected from
+// the IP address are unaffected.
+func (s *Spectrum) BanIP(ip string, duration time.Duration) {
+	s.throttle.ban(ip, duration)
+}
+
+// UnbanIP lifts the ban of the IP address set using BanIP or due to exceeding util.Opts.ConnectionRateLimit.
+func (s *Spectrum) UnbanIP(ip string) {
+	s.throttle.unban(ip)
+}
+
 // Close closes the listener and stops listening for incoming connections.
 func (s *Spectrum) Close() error {
 	for _, activeSession := range s.registry.GetSessions() {
 		activeSession.Disconnect(s.opts.ShutdownMessage)
 	}
+	for _, pendingSession := range s.pendingLogins() {
+		pendingSession.Disconnect(s.opts.ShutdownMessage)
+	}
 	return s.listener.Close()
 }
+
+// Shutdown gracefully shuts down Spectrum. Connections accepted from then on are refused, and every session is
+// either transferred to the proxy at util.Opts.ShutdownTransferAddr, or disconnected with the shutdown message
+// if it is not set. Shutdown then waits for all sessions to close, including the ones still logging in, before
+// closing the listener. If the context is done first, the remaining sessions are disconnected and the context's
+// error is returned.
+func (s *Spectrum) Shutdown(ctx context.Context) error {
+	s.shuttingDown.Store(true)
+	drained := make(map[*session.Session]struct{})
+	ticker := time.NewTicker(time.Millisecond * 50)
+	defer ticker.Stop()
+	for {
+		// Sessions that were still logging in when the shutdown started are drained once they are registered.
+		for _, activeSession := range s.registry.GetSessions() {
+			if _, ok := drained[activeSession]; !ok {
+				drained[activeSession] = struct{}{}
+				s.drain(activeSession)
+			}
+		}
+
+		if s.registry.Count() == 0 && len(s.pendingLogins()) == 0 {
+			return s.listener.Close()
+		}
+
+		select {
+		case <-ctx.Done():
+			_ = s.Close()
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// pendingLogins returns the sessions accepted that have neither been added to the registry nor closed yet.
+func (s *Spectrum) pendingLogins() []*session.Session {
+	s.loginsMu.Lock()
+	defer s.loginsMu.Unlock()
+	sessions := make([]*session.Session, 0, len(s.logins))
+	for pendingSession := range s.logins {
+		sessions = append(sessions, pendingSession)
+	}
+	return sessions
+}
+
+// finishLogin marks the login of the session as no longer in flight.
+func (s *Spectrum) finishLogin(finished *session.Session) {
+	s.loginsMu.Lock()
+	defer s.loginsMu.Unlock()
+	delete(s.logins, finished)
+}
+
+// drain transfers the session to util.Opts.ShutdownTransferAddr, or disconnects it if it is not set or invalid.
+func (s *Spectrum) drain(activeSession *session.Session) {
+	host, port, err := net.SplitHostPort(s.opts.ShutdownTransferAddr)
+	if err != nil {
+		activeSession.Disconnect(s.opts.ShutdownMessage)
+		return
+	}
+
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		activeSession.Disconnect(s.opts.ShutdownMessage)
+		return
+	}
+	// The client disconnects from the proxy once it receives the transfer, closing the session.
+	_ = activeSession.Client().WritePacket(&packet.Transfer{Address: host, Port: uint16(p)})
+	_ = activeSession.Client().Flush()
+}
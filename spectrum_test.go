@@ -0,0 +1,178 @@
+package spectrum
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cooldogedev/spectrum/server"
+	"github.com/cooldogedev/spectrum/session"
+	"github.com/cooldogedev/spectrum/util"
+	"github.com/sandertv/gophertunnel/minecraft"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+)
+
+// newTestSpectrum returns a Spectrum listening on a random local port without authentication, which does not log
+// sessions in automatically. It is closed once the test finishes.
+func newTestSpectrum(t *testing.T, opts util.Opts) *Spectrum {
+	t.Helper()
+	opts.Addr = "127.0.0.1:0"
+	opts.AutoLogin = false
+	s := NewSpectrum(server.NewStaticDiscovery("server", "server"), slog.New(slog.NewTextHandler(io.Discard, nil)), &opts, nil)
+	if err := s.Listen(minecraft.ListenConfig{AuthenticationDisabled: true}); err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+// connect connects a client to the Spectrum, returning the session accepted and the connection of the client.
+// The session is not logged in, and is only added to the registry if register is true.
+func connect(t *testing.T, s *Spectrum, register bool) (*session.Session, *minecraft.Conn) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	dialed := make(chan *minecraft.Conn, 1)
+	go func() {
+		conn, _ := (minecraft.Dialer{}).DialContext(ctx, "raknet", s.Listener().Addr().String())
+		dialed <- conn
+	}()
+
+	accepted, err := s.Accept()
+	if err != nil {
+		t.Fatalf("Accept() error = %v", err)
+	}
+	if err := accepted.Client().StartGame(minecraft.GameData{}); err != nil {
+		t.Fatalf("failed to start game: %v", err)
+	}
+
+	client := <-dialed
+	if client == nil {
+		t.Fatal("client did not spawn")
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	if register {
+		s.Registry().AddSession(client.IdentityData().XUID, accepted)
+	}
+	return accepted, client
+}
+
+// shutdown shuts the Spectrum down in the background using the context, returning a channel receiving the
+// error returned.
+func shutdown(ctx context.Context, s *Spectrum) <-chan error {
+	errs := make(chan error, 1)
+	go func() {
+		errs <- s.Shutdown(ctx)
+	}()
+	return errs
+}
+
+// waitShutdown waits for the shutdown to finish, failing the test if it does not within 10 seconds.
+func waitShutdown(t *testing.T, errs <-chan error) error {
+	t.Helper()
+	select {
+	case err := <-errs:
+		return err
+	case <-time.After(time.Second * 10):
+		t.Fatal("Shutdown() did not return")
+		return nil
+	}
+}
+
+func TestSpectrumShutdownDrain(t *testing.T) {
+	tests := []struct {
+		name         string
+		transferAddr string
+		wantTransfer bool
+	}{
+		{name: "transfer", transferAddr: "127.0.0.1:19133", wantTransfer: true},
+		{name: "no transfer address", wantTransfer: false},
+		{name: "invalid transfer address", transferAddr: "127.0.0.1", wantTransfer: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := *util.DefaultOpts()
+			opts.ShutdownTransferAddr = tt.transferAddr
+			s := newTestSpectrum(t, opts)
+			accepted, client := connect(t, s, true)
+			errs := shutdown(context.Background(), s)
+
+			_ = client.SetReadDeadline(time.Now().Add(time.Second * 10))
+			for {
+				pk, err := client.ReadPacket()
+				if err != nil {
+					if tt.wantTransfer {
+						t.Fatalf("failed to read packet: %v", err)
+					}
+					if !strings.Contains(err.Error(), opts.ShutdownMessage) {
+						t.Fatalf("client disconnected with %v, want %q", err, opts.ShutdownMessage)
+					}
+					break
+				}
+
+				if pk, ok := pk.(*packet.Transfer); ok {
+					if !tt.wantTransfer {
+						t.Fatalf("client was transferred to %v:%v, want disconnect", pk.Address, pk.Port)
+					}
+					if pk.Address != "127.0.0.1" || pk.Port != 19133 {
+						t.Fatalf("client was transferred to %v:%v, want %v", pk.Address, pk.Port, tt.transferAddr)
+					}
+					// The session is closed as it would be once the client left the proxy.
+					_ = accepted.Close()
+					break
+				}
+			}
+
+			if err := waitShutdown(t, errs); err != nil {
+				t.Fatalf("Shutdown() error = %v", err)
+			}
+			if _, err := s.Listener().Accept(); err == nil {
+				t.Fatal("Shutdown() did not close the listener")
+			}
+		})
+	}
+}
+
+func TestSpectrumShutdownPendingLogin(t *testing.T) {
+	s := newTestSpectrum(t, *util.DefaultOpts())
+	accepted, client := connect(t, s, false)
+	errs := shutdown(context.Background(), s)
+	select {
+	case err := <-errs:
+		t.Fatalf("Shutdown() = %v while a session was logging in", err)
+	case <-time.After(time.Millisecond * 200):
+	}
+
+	_ = client.Close()
+	if err := waitShutdown(t, errs); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if accepted.Context().Err() == nil {
+		t.Fatal("session was not closed")
+	}
+}
+
+func TestSpectrumShutdownDeadline(t *testing.T) {
+	opts := *util.DefaultOpts()
+	opts.ShutdownTransferAddr = "127.0.0.1:19133"
+	s := newTestSpectrum(t, opts)
+	// The clients ignore the transfer, so the sessions remain until they are closed once the context is done.
+	registered, _ := connect(t, s, true)
+	pending, _ := connect(t, s, false)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*200)
+	defer cancel()
+	if err := waitShutdown(t, shutdown(ctx, s)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Shutdown() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if registered.Context().Err() == nil || pending.Context().Err() == nil {
+		t.Fatal("Shutdown() did not close the remaining sessions")
+	}
+	if s.Registry().Count() != 0 {
+		t.Fatalf("Registry().Count() = %v, want 0", s.Registry().Count())
+	}
+}
@@ -0,0 +1,197 @@
+package spectrum
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/cooldogedev/spectrum/util"
+	"github.com/sandertv/go-raknet"
+	"github.com/sandertv/gophertunnel/minecraft"
+)
+
+// connThrottle limits the connections accepted by the listener of a Spectrum according to the connection limits
+// of util.Opts, before their login handshake starts.
+type connThrottle struct {
+	opts util.Opts
+
+	windows    map[string]*connWindow
+	handshakes map[string]struct{}
+	bans       map[string]time.Time
+	lastPurge  time.Time
+	mu         sync.Mutex
+}
+
+// connWindow counts the connections of a single IP address within a window.
+type connWindow struct {
+	start time.Time
+	count int
+}
+
+func newConnThrottle(opts util.Opts) *connThrottle {
+	return &connThrottle{
+		opts:       opts,
+		windows:    make(map[string]*connWindow),
+		handshakes: make(map[string]struct{}),
+		bans:       make(map[string]time.Time),
+	}
+}
+
+// allow reports whether a connection from the address may be accepted, registering its handshake if so. IP
+// addresses exceeding util.Opts.ConnectionRateLimit are banned for util.Opts.ConnectionBanDuration.
+func (t *connThrottle) allow(addr net.Addr) bool {
+	ip := addrIP(addr)
+	now := time.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	window := time.Millisecond * time.Duration(t.opts.ConnectionRateWindow)
+	t.purge(now, window)
+	if expiry, ok := t.bans[ip]; ok && now.Before(expiry) {
+		return false
+	}
+
+	if t.opts.ConnectionRateLimit > 0 {
+		w, ok := t.windows[ip]
+		if !ok || now.Sub(w.start) > window {
+			w = &connWindow{start: now}
+			t.windows[ip] = w
+		}
+
+		w.count++
+		if w.count > t.opts.ConnectionRateLimit {
+			if t.opts.ConnectionBanDuration > 0 {
+				t.bans[ip] = now.Add(time.Millisecond * time.Duration(t.opts.ConnectionBanDuration))
+			}
+			return false
+		}
+	}
+
+	if t.opts.MaxHandshakes > 0 && len(t.handshakes) >= t.opts.MaxHandshakes {
+		return false
+	}
+	t.handshakes[addr.String()] = struct{}{}
+	return true
+}
+
+// release marks the handshake of the connection from the address as finished, either because the connection was
+// accepted by the proxy or because it was closed.
+func (t *connThrottle) release(addr net.Addr) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.handshakes, addr.String())
+}
+
+// ban refuses connections from the IP address until the duration has passed.
+func (t *connThrottle) ban(ip string, duration time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.bans[ip] = time.Now().Add(duration)
+}
+
+// unban lifts the ban of the IP address.
+func (t *connThrottle) unban(ip string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.bans, ip)
+}
+
+// purge removes the expired windows and bans, at most once per window.
+func (t *connThrottle) purge(now time.Time, window time.Duration) {
+	if now.Sub(t.lastPurge) < max(window, time.Second) {
+		return
+	}
+
+	t.lastPurge = now
+	for ip, w := range t.windows {
+		if now.Sub(w.start) > window {
+			delete(t.windows, ip)
+		}
+	}
+	for ip, expiry := range t.bans {
+		if !now.Before(expiry) {
+			delete(t.bans, ip)
+		}
+	}
+}
+
+// addrIP returns the IP address of the network address, or the address itself if it has no port.
+func addrIP(addr net.Addr) string {
+	if udp, ok := addr.(*net.UDPAddr); ok {
+		return udp.IP.String()
+	}
+
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
+
+// throttledNetwork is a RakNet minecraft.Network refusing the connections disallowed by a connThrottle.
+type throttledNetwork struct {
+	logger   *slog.Logger
+	throttle *connThrottle
+}
+
+// DialContext ...
+func (n throttledNetwork) DialContext(ctx context.Context, address string) (net.Conn, error) {
+	return raknet.Dialer{ErrorLog: n.logger.With("net origin", "raknet")}.DialContext(ctx, address)
+}
+
+// PingContext ...
+func (n throttledNetwork) PingContext(ctx context.Context, address string) (response []byte, err error) {
+	return raknet.Dialer{ErrorLog: n.logger.With("net origin", "raknet")}.PingContext(ctx, address)
+}
+
+// Listen ...
+func (n throttledNetwork) Listen(address string) (minecraft.NetworkListener, error) {
+	listener, err := raknet.ListenConfig{ErrorLog: n.logger.With("net origin", "raknet")}.Listen(address)
+	if err != nil {
+		return nil, err
+	}
+	return &throttledListener{NetworkListener: listener, throttle: n.throttle}, nil
+}
+
+// throttledListener is a minecraft.NetworkListener closing the connections disallowed by a connThrottle as soon
+// as they are accepted.
+type throttledListener struct {
+	minecraft.NetworkListener
+	throttle *connThrottle
+}
+
+// Accept ...
+func (l *throttledListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.NetworkListener.Accept()
+		if err != nil {
+			return nil, err
+		}
+
+		if !l.throttle.allow(conn.RemoteAddr()) {
+			_ = conn.Close()
+			continue
+		}
+
+		c, ok := conn.(*raknet.Conn)
+		if !ok {
+			l.throttle.release(conn.RemoteAddr())
+			return conn, nil
+		}
+		return &throttledConn{Conn: c, throttle: l.throttle}, nil
+	}
+}
+
+// throttledConn is a connection accepted by a throttledListener, releasing its handshake once it is closed. The
+// RakNet connection is embedded so that the methods gophertunnel relies on, such as Latency, remain available.
+type throttledConn struct {
+	*raknet.Conn
+	throttle *connThrottle
+}
+
+// Close ...
+func (c *throttledConn) Close() error {
+	c.throttle.release(c.RemoteAddr())
+	return c.Conn.Close()
+}
@@ -0,0 +1,128 @@
+package spectrum
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/cooldogedev/spectrum/util"
+)
+
+// throttleStep is a step of a connThrottle test, either checking whether a connection from addr is allowed or,
+// if set, releasing, banning or unbanning an address. The duration is waited before the step is performed.
+type throttleStep struct {
+	wait time.Duration
+
+	addr string
+	want bool
+
+	release string
+	ban     string
+	unban   string
+}
+
+func TestConnThrottle(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  func(opts *util.Opts)
+		steps []throttleStep
+	}{
+		{
+			name: "unlimited",
+			steps: []throttleStep{
+				{addr: "192.0.2.1:1", want: true},
+				{addr: "192.0.2.1:2", want: true},
+				{addr: "192.0.2.1:3", want: true},
+			},
+		},
+		{
+			name: "rate limit",
+			opts: func(opts *util.Opts) { opts.ConnectionRateLimit, opts.ConnectionRateWindow = 2, 50 },
+			steps: []throttleStep{
+				{addr: "192.0.2.1:1", want: true},
+				{addr: "192.0.2.1:2", want: true},
+				{addr: "192.0.2.1:3"},
+				{addr: "192.0.2.2:1", want: true},
+				{wait: time.Millisecond * 100, addr: "192.0.2.1:4", want: true},
+			},
+		},
+		{
+			name: "auto ban",
+			opts: func(opts *util.Opts) {
+				opts.ConnectionRateLimit, opts.ConnectionRateWindow, opts.ConnectionBanDuration = 1, 50, 60_000
+			},
+			steps: []throttleStep{
+				{addr: "192.0.2.1:1", want: true},
+				{addr: "192.0.2.1:2"},
+				{wait: time.Millisecond * 100, addr: "192.0.2.1:3"},
+				{addr: "192.0.2.2:1", want: true},
+				{unban: "192.0.2.1"},
+				{addr: "192.0.2.1:4", want: true},
+			},
+		},
+		{
+			name: "max handshakes",
+			opts: func(opts *util.Opts) { opts.MaxHandshakes = 1 },
+			steps: []throttleStep{
+				{addr: "192.0.2.1:1", want: true},
+				{addr: "192.0.2.2:1"},
+				{release: "192.0.2.1:1"},
+				{addr: "192.0.2.2:1", want: true},
+			},
+		},
+		{
+			name: "ban",
+			steps: []throttleStep{
+				{ban: "192.0.2.1"},
+				{addr: "192.0.2.1:1"},
+				{addr: "192.0.2.2:1", want: true},
+				{unban: "192.0.2.1"},
+				{addr: "192.0.2.1:2", want: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := *util.DefaultOpts()
+			if tt.opts != nil {
+				tt.opts(&opts)
+			}
+			throttle := newConnThrottle(opts)
+			s := &Spectrum{throttle: throttle}
+			for i, step := range tt.steps {
+				time.Sleep(step.wait)
+				switch {
+				case step.release != "":
+					throttle.release(testAddr(t, step.release))
+				case step.ban != "":
+					s.BanIP(step.ban, time.Minute)
+				case step.unban != "":
+					s.UnbanIP(step.unban)
+				default:
+					if got := throttle.allow(testAddr(t, step.addr)); got != step.want {
+						t.Fatalf("step %v: allow(%v) = %v, want %v", i, step.addr, got, step.want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestConnThrottleBanExpired(t *testing.T) {
+	throttle := newConnThrottle(*util.DefaultOpts())
+	(&Spectrum{throttle: throttle}).BanIP("192.0.2.1", time.Millisecond*10)
+	time.Sleep(time.Millisecond * 20)
+	if !throttle.allow(testAddr(t, "192.0.2.1:1")) {
+		t.Fatal("allow() refused a connection after its ban expired")
+	}
+}
+
+// testAddr returns the UDP address of the string.
+func testAddr(t *testing.T, addr string) net.Addr {
+	t.Helper()
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		t.Fatalf("ResolveUDPAddr() error = %v", err)
+	}
+	return udpAddr
+}
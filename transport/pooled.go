@@ -0,0 +1,187 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// pooledDialTimeout is the timeout of dials performed in the background to refill a pool.
+const pooledDialTimeout = time.Second * 10
+
+// Pooled wraps a Transport, keeping connections to the servers it dials open in advance so that later dials to
+// the same servers are served by an already established connection rather than paying the dial and handshake
+// latency. Each pool is filled once an address is dialed or warmed for the first time, and connections left idle
+// for longer than the idle timeout are closed, either once they are found while dialing or periodically.
+type Pooled struct {
+	transport   Transport
+	logger      *slog.Logger
+	size        int
+	idleTimeout time.Duration
+
+	pools   map[string][]pooledConn
+	pending map[string]int
+	closed  bool
+	mu      sync.Mutex
+
+	cancelFunc context.CancelFunc
+	ctx        context.Context
+}
+
+// pooledConn is an idle connection held by a Pooled transport.
+type pooledConn struct {
+	conn    io.ReadWriteCloser
+	created time.Time
+}
+
+// NewPooled creates a new Pooled transport keeping up to size connections per address, dialed using the provided
+// transport. An idle timeout of 0 keeps idle connections open until they are used or the transport is closed.
+func NewPooled(transport Transport, logger *slog.Logger, size int, idleTimeout time.Duration) (*Pooled, error) {
+	if size <= 0 {
+		return nil, errors.New("pool size must be positive")
+	}
+	if idleTimeout < 0 {
+		return nil, errors.New("idle timeout must not be negative")
+	}
+
+	p := &Pooled{
+		transport:   transport,
+		logger:      logger,
+		size:        size,
+		idleTimeout: idleTimeout,
+
+		pools:   make(map[string][]pooledConn),
+		pending: make(map[string]int),
+	}
+	p.ctx, p.cancelFunc = context.WithCancel(context.Background())
+	if idleTimeout > 0 {
+		go p.reap()
+	}
+	return p, nil
+}
+
+// Dial ...
+func (p *Pooled) Dial(ctx context.Context, addr string) (io.ReadWriteCloser, error) {
+	p.mu.Lock()
+	conn := p.take(addr)
+	p.fill(addr)
+	p.mu.Unlock()
+	if conn != nil {
+		return conn, nil
+	}
+	return p.transport.Dial(ctx, addr)
+}
+
+// Warm fills the pool of the provided address in the background, without waiting for the address to be dialed.
+func (p *Pooled) Warm(addr string) {
+	p.mu.Lock()
+	p.fill(addr)
+	p.mu.Unlock()
+}
+
+// Close closes all the idle connections held by the transport. Connections still being dialed in the background
+// are closed once they are established, and the pools are no longer filled.
+func (p *Pooled) Close() error {
+	p.cancelFunc()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	for addr, pool := range p.pools {
+		for _, c := range pool {
+			_ = c.conn.Close()
+		}
+		delete(p.pools, addr)
+	}
+	return nil
+}
+
+// take takes the most recently created connection that has not expired from the pool of the address, closing
+// the expired connections it finds.
+func (p *Pooled) take(addr string) io.ReadWriteCloser {
+	pool := p.pools[addr]
+	for len(pool) > 0 {
+		c := pool[len(pool)-1]
+		pool = pool[:len(pool)-1]
+		if !p.expired(c) {
+			p.pools[addr] = pool
+			return c.conn
+		}
+		_ = c.conn.Close()
+	}
+	p.pools[addr] = pool
+	return nil
+}
+
+// reap closes the connections that have been idle for longer than the idle timeout at every idle timeout, until
+// the transport is closed.
+func (p *Pooled) reap() {
+	ticker := time.NewTicker(p.idleTimeout)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		p.mu.Lock()
+		for addr, pool := range p.pools {
+			idle := pool[:0]
+			for _, c := range pool {
+				if !p.expired(c) {
+					idle = append(idle, c)
+				} else {
+					_ = c.conn.Close()
+				}
+			}
+			if len(idle) == 0 {
+				delete(p.pools, addr)
+			} else {
+				p.pools[addr] = idle
+			}
+		}
+		p.mu.Unlock()
+	}
+}
+
+// expired reports whether the connection has been idle for longer than the idle timeout. Connections never expire
+// if the idle timeout is 0.
+func (p *Pooled) expired(c pooledConn) bool {
+	return p.idleTimeout > 0 && time.Since(c.created) >= p.idleTimeout
+}
+
+// fill dials connections in the background until the pool of the address holds size connections.
+func (p *Pooled) fill(addr string) {
+	if p.closed {
+		return
+	}
+
+	for i := len(p.pools[addr]) + p.pending[addr]; i < p.size; i++ {
+		p.pending[addr]++
+		go p.dialIdle(addr)
+	}
+}
+
+// dialIdle dials a connection to the address and adds it to its pool.
+func (p *Pooled) dialIdle(addr string) {
+	ctx, cancel := context.WithTimeout(p.ctx, pooledDialTimeout)
+	defer cancel()
+	conn, err := p.transport.Dial(ctx, addr)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pending[addr]--
+	if err != nil {
+		p.logger.Debug("failed to dial pooled connection", "addr", addr, "err", err)
+		return
+	}
+
+	if p.closed {
+		_ = conn.Close()
+		return
+	}
+	p.pools[addr] = append(p.pools[addr], pooledConn{conn: conn, created: time.Now()})
+}
@@ -0,0 +1,160 @@
+package transport
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testConn is a connection recording whether it was closed.
+type testConn struct {
+	closed atomic.Bool
+}
+
+// Read ...
+func (c *testConn) Read([]byte) (int, error) {
+	return 0, io.EOF
+}
+
+// Write ...
+func (c *testConn) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+// Close ...
+func (c *testConn) Close() error {
+	c.closed.Store(true)
+	return nil
+}
+
+// testTransport is a transport recording the connections it dialed.
+type testTransport struct {
+	conns []*testConn
+	mu    sync.Mutex
+}
+
+// Dial ...
+func (t *testTransport) Dial(context.Context, string) (io.ReadWriteCloser, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	conn := &testConn{}
+	t.conns = append(t.conns, conn)
+	return conn, nil
+}
+
+// dialed returns the connections dialed so far.
+func (t *testTransport) dialed() []*testConn {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return slices.Clone(t.conns)
+}
+
+// newTestPooled returns a Pooled transport dialing through the transport, which is closed once the test finishes.
+func newTestPooled(t *testing.T, transport Transport, size int, idleTimeout time.Duration) *Pooled {
+	t.Helper()
+	p, err := NewPooled(transport, slog.New(slog.NewTextHandler(io.Discard, nil)), size, idleTimeout)
+	if err != nil {
+		t.Fatalf("NewPooled() error = %v", err)
+	}
+	t.Cleanup(func() { _ = p.Close() })
+	return p
+}
+
+func TestNewPooled(t *testing.T) {
+	tests := []struct {
+		name        string
+		size        int
+		idleTimeout time.Duration
+		wantErr     bool
+	}{
+		{name: "valid", size: 2, idleTimeout: time.Minute},
+		{name: "no idle timeout", size: 2},
+		{name: "zero size", wantErr: true},
+		{name: "negative size", size: -1, wantErr: true},
+		{name: "negative idle timeout", size: 2, idleTimeout: -time.Minute, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewPooled(&testTransport{}, slog.New(slog.NewTextHandler(io.Discard, nil)), tt.size, tt.idleTimeout)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewPooled() error = %v, want error %v", err, tt.wantErr)
+			}
+			if p != nil {
+				_ = p.Close()
+			}
+		})
+	}
+}
+
+func TestPooledDial(t *testing.T) {
+	tests := []struct {
+		name        string
+		idleTimeout time.Duration
+		idle        time.Duration
+		wantReused  bool
+	}{
+		{name: "no idle timeout", idle: time.Hour, wantReused: true},
+		{name: "fresh", idleTimeout: time.Minute, wantReused: true},
+		{name: "expired", idleTimeout: time.Minute, idle: time.Minute * 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestPooled(t, &testTransport{}, 1, tt.idleTimeout)
+			pooled := &testConn{}
+			p.mu.Lock()
+			p.pools["server"] = []pooledConn{{conn: pooled, created: time.Now().Add(-tt.idle)}}
+			p.mu.Unlock()
+
+			conn, err := p.Dial(context.Background(), "server")
+			if err != nil {
+				t.Fatalf("Dial() error = %v", err)
+			}
+			if reused := conn == pooled; reused != tt.wantReused {
+				t.Fatalf("pooled connection reused = %v, want %v", reused, tt.wantReused)
+			}
+			if closed := pooled.closed.Load(); closed == tt.wantReused {
+				t.Fatalf("pooled connection closed = %v, want %v", closed, !tt.wantReused)
+			}
+		})
+	}
+}
+
+func TestPooledWarm(t *testing.T) {
+	transport := &testTransport{}
+	p := newTestPooled(t, transport, 2, 0)
+	p.Warm("server")
+	deadline := time.Now().Add(time.Second * 10)
+	for {
+		p.mu.Lock()
+		filled := len(p.pools["server"]) == 2
+		p.mu.Unlock()
+		if filled {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("pool was not filled")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	warmed := transport.dialed()
+	conn, err := p.Dial(context.Background(), "server")
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	if !slices.Contains(warmed, conn.(*testConn)) {
+		t.Fatal("Dial() dialed a new connection rather than using a warmed one")
+	}
+
+	_ = p.Close()
+	for _, c := range warmed {
+		if c != conn && !c.closed.Load() {
+			t.Fatal("Close() kept an idle connection open")
+		}
+	}
+}
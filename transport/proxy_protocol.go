@@ -0,0 +1,106 @@
+package transport
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+)
+
+// proxyProtocolSignature is the signature starting every PROXY protocol v2 header.
+var proxyProtocolSignature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+const (
+	proxyProtocolCommandLocal = 0x20
+	proxyProtocolCommandProxy = 0x21
+
+	proxyProtocolFamilyUDPv4 = 0x12
+	proxyProtocolFamilyUDPv6 = 0x22
+)
+
+type clientAddrKey struct{}
+
+// clientAddr holds the addresses of the client a server is dialed for.
+type clientAddr struct {
+	remote net.Addr
+	local  net.Addr
+}
+
+// WithClientAddr returns a copy of the context carrying the remote address of the client a server is dialed for,
+// along with the local address the client connected to.
+func WithClientAddr(ctx context.Context, remote, local net.Addr) context.Context {
+	return context.WithValue(ctx, clientAddrKey{}, clientAddr{remote: remote, local: local})
+}
+
+// ClientAddr returns the client addresses carried by the context, if any.
+func ClientAddr(ctx context.Context) (remote, local net.Addr, ok bool) {
+	addr, ok := ctx.Value(clientAddrKey{}).(clientAddr)
+	return addr.remote, addr.local, ok
+}
+
+// ProxyProtocol wraps a Transport, sending a PROXY protocol v2 header carrying the address of the client on
+// every connection it dials, so that servers see the real address of their players. The client's address is
+// taken from the context passed to Dial, see WithClientAddr, and connections dialed without one are sent a
+// LOCAL header instead. When combined with Pooled, the Pooled transport must be the one wrapped, as its
+// connections are dialed ahead of time for no particular client.
+type ProxyProtocol struct {
+	transport Transport
+}
+
+// NewProxyProtocol creates a new ProxyProtocol transport dialing connections using the provided transport.
+func NewProxyProtocol(transport Transport) *ProxyProtocol {
+	return &ProxyProtocol{transport: transport}
+}
+
+// Dial ...
+func (p *ProxyProtocol) Dial(ctx context.Context, addr string) (io.ReadWriteCloser, error) {
+	conn, err := p.transport.Dial(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	remote, local, _ := ClientAddr(ctx)
+	if _, err := conn.Write(proxyProtocolHeader(remote, local)); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed to write proxy protocol header: %w", err)
+	}
+	return conn, nil
+}
+
+// proxyProtocolHeader encodes a PROXY protocol v2 header for the provided client addresses. A LOCAL header is
+// returned if either address is missing or has no IP address.
+func proxyProtocolHeader(remote, local net.Addr) []byte {
+	header := append([]byte(nil), proxyProtocolSignature...)
+	srcIP, srcPort, srcOk := addrIP(remote)
+	dstIP, dstPort, dstOk := addrIP(local)
+	if !srcOk || !dstOk {
+		return append(header, proxyProtocolCommandLocal, 0, 0, 0)
+	}
+
+	family := byte(proxyProtocolFamilyUDPv6)
+	if src4, dst4 := srcIP.To4(), dstIP.To4(); src4 != nil && dst4 != nil {
+		family, srcIP, dstIP = proxyProtocolFamilyUDPv4, src4, dst4
+	} else {
+		srcIP, dstIP = srcIP.To16(), dstIP.To16()
+	}
+
+	header = append(header, proxyProtocolCommandProxy, family)
+	header = binary.BigEndian.AppendUint16(header, uint16(len(srcIP)*2+4))
+	header = append(header, srcIP...)
+	header = append(header, dstIP...)
+	header = binary.BigEndian.AppendUint16(header, uint16(srcPort))
+	return binary.BigEndian.AppendUint16(header, uint16(dstPort))
+}
+
+// addrIP returns the IP address and port of the address, if it has one.
+func addrIP(addr net.Addr) (net.IP, int, bool) {
+	switch addr := addr.(type) {
+	case *net.UDPAddr:
+		return addr.IP, addr.Port, addr.IP != nil
+	case *net.TCPAddr:
+		return addr.IP, addr.Port, addr.IP != nil
+	default:
+		return nil, 0, false
+	}
+}
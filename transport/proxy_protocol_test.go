@@ -0,0 +1,106 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"io"
+	"net"
+	"testing"
+)
+
+const proxyProtocolSignatureHex = "0d0a0d0a000d0a515549540a"
+
+func TestProxyProtocolHeader(t *testing.T) {
+	tests := []struct {
+		name   string
+		remote net.Addr
+		local  net.Addr
+		want   string
+	}{
+		{
+			name:   "ipv4",
+			remote: &net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 19132},
+			local:  &net.UDPAddr{IP: net.ParseIP("10.0.0.1"), Port: 19133},
+			want:   "2112" + "000c" + "c0000201" + "0a000001" + "4abc" + "4abd",
+		},
+		{
+			name:   "ipv6",
+			remote: &net.UDPAddr{IP: net.ParseIP("2001:db8::1"), Port: 19132},
+			local:  &net.UDPAddr{IP: net.ParseIP("::1"), Port: 19133},
+			want:   "2122" + "0024" + "20010db8000000000000000000000001" + "00000000000000000000000000000001" + "4abc" + "4abd",
+		},
+		{
+			name:   "mixed families",
+			remote: &net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 19132},
+			local:  &net.UDPAddr{IP: net.ParseIP("::1"), Port: 19133},
+			want:   "2122" + "0024" + "00000000000000000000ffffc0000201" + "00000000000000000000000000000001" + "4abc" + "4abd",
+		},
+		{
+			name:   "tcp",
+			remote: &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 19132},
+			local:  &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 19133},
+			want:   "2112" + "000c" + "c0000201" + "0a000001" + "4abc" + "4abd",
+		},
+		{name: "no addresses", want: "2000" + "0000"},
+		{name: "no local address", remote: &net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 19132}, want: "2000" + "0000"},
+		{
+			name:   "no ip",
+			remote: &net.UnixAddr{Name: "/tmp/client.sock", Net: "unix"},
+			local:  &net.UDPAddr{IP: net.ParseIP("10.0.0.1"), Port: 19133},
+			want:   "2000" + "0000",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, _ := hex.DecodeString(proxyProtocolSignatureHex + tt.want)
+			if got := proxyProtocolHeader(tt.remote, tt.local); !bytes.Equal(got, want) {
+				t.Fatalf("proxyProtocolHeader() = %x, want %x", got, want)
+			}
+		})
+	}
+}
+
+// recordingConn is a connection recording the data written to it.
+type recordingConn struct {
+	testConn
+	written bytes.Buffer
+}
+
+// Write ...
+func (c *recordingConn) Write(p []byte) (int, error) {
+	return c.written.Write(p)
+}
+
+// recordingTransport is a transport dialing the same recordingConn.
+type recordingTransport struct {
+	conn *recordingConn
+}
+
+// Dial ...
+func (t recordingTransport) Dial(context.Context, string) (io.ReadWriteCloser, error) {
+	return t.conn, nil
+}
+
+func TestProxyProtocolDial(t *testing.T) {
+	remote, local := &net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 19132}, &net.UDPAddr{IP: net.ParseIP("10.0.0.1"), Port: 19133}
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want []byte
+	}{
+		{name: "client address", ctx: WithClientAddr(context.Background(), remote, local), want: proxyProtocolHeader(remote, local)},
+		{name: "no client address", ctx: context.Background(), want: proxyProtocolHeader(nil, nil)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := &recordingConn{}
+			if _, err := NewProxyProtocol(recordingTransport{conn: conn}).Dial(tt.ctx, "server"); err != nil {
+				t.Fatalf("Dial() error = %v", err)
+			}
+			if !bytes.Equal(conn.written.Bytes(), tt.want) {
+				t.Fatalf("Dial() wrote %x, want %x", conn.written.Bytes(), tt.want)
+			}
+		})
+	}
+}
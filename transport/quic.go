@@ -17,9 +17,10 @@ import (
 // By leveraging streams for individual server connections, it enhances overall performance and
 // resource utilization.
 type QUIC struct {
-	connections map[string]*quic.Conn
-	logger      *slog.Logger
-	mu          sync.Mutex
+	connections  map[string]*quic.Conn
+	logger       *slog.Logger
+	sessionCache tls.ClientSessionCache
+	mu           sync.Mutex
 }
 
 // NewQUIC creates a new QUIC transport instance.
@@ -30,6 +31,18 @@ func NewQUIC(logger *slog.Logger) *QUIC {
 	}
 }
 
+// NewQUICZeroRTT creates a new QUIC transport instance which caches the TLS session tickets issued by servers,
+// using them to resume later connections to the same servers with 0-RTT. This removes the handshake latency of
+// reconnecting to a server once its previous connection has been closed, as long as the server accepts 0-RTT.
+// Data sent with 0-RTT can be replayed by an attacker, so it should only be used within trusted networks.
+func NewQUICZeroRTT(logger *slog.Logger) *QUIC {
+	return &QUIC{
+		connections:  make(map[string]*quic.Conn),
+		logger:       logger,
+		sessionCache: tls.NewLRUClientSessionCache(0),
+	}
+}
+
 // Dial ...
 func (q *QUIC) Dial(ctx context.Context, addr string) (io.ReadWriteCloser, error) {
 	q.mu.Lock()
@@ -37,12 +50,18 @@ func (q *QUIC) Dial(ctx context.Context, addr string) (io.ReadWriteCloser, error
 
 	conn, ok := q.connections[addr]
 	if !ok {
-		c, err := quic.DialAddr(
+		dial := quic.DialAddr
+		if q.sessionCache != nil {
+			dial = quic.DialAddrEarly
+		}
+
+		c, err := dial(
 			ctx,
 			addr,
 			&tls.Config{
 				InsecureSkipVerify: true,
 				NextProtos:         []string{"spectrum"},
+				ClientSessionCache: q.sessionCache,
 			},
 			&quic.Config{
 				MaxIdleTimeout:                 time.Second * 10,
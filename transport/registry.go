@@ -0,0 +1,54 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Registry implements the Transport interface, dialing every address using the transport registered for its
+// scheme, such as tcp://127.0.0.1:19133 or unix:///run/server.sock. The scheme is stripped from the address
+// before it is passed to the transport, and addresses without a scheme are dialed using the fallback transport.
+type Registry struct {
+	fallback   Transport
+	transports map[string]Transport
+	mu         sync.RWMutex
+}
+
+// NewRegistry creates a new Registry dialing addresses without a scheme using the provided fallback transport,
+// which may be nil to reject such addresses.
+func NewRegistry(fallback Transport) *Registry {
+	return &Registry{
+		fallback:   fallback,
+		transports: make(map[string]Transport),
+	}
+}
+
+// Register registers the transport used to dial addresses with the provided scheme, replacing any transport
+// previously registered for it.
+func (r *Registry) Register(scheme string, transport Transport) {
+	r.mu.Lock()
+	r.transports[strings.ToLower(scheme)] = transport
+	r.mu.Unlock()
+}
+
+// Dial ...
+func (r *Registry) Dial(ctx context.Context, addr string) (io.ReadWriteCloser, error) {
+	scheme, rest, ok := strings.Cut(addr, "://")
+	if !ok {
+		if r.fallback == nil {
+			return nil, fmt.Errorf("missing scheme in address %v", addr)
+		}
+		return r.fallback.Dial(ctx, addr)
+	}
+
+	r.mu.RLock()
+	transport, ok := r.transports[strings.ToLower(scheme)]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no transport registered for scheme %v", scheme)
+	}
+	return transport.Dial(ctx, rest)
+}
@@ -0,0 +1,146 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Resolver resolves server addresses before they are dialed. Addresses without a port are resolved using the
+// _spectrum._tcp SRV records of their host, while IP literals, including bracketed IPv6 addresses, are
+// normalised. Looked up SRV records are cached for the configured TTL to avoid a lookup on every dial.
+type Resolver struct {
+	resolver *net.Resolver
+	ttl      time.Duration
+
+	cache map[string]resolverEntry
+	mu    sync.Mutex
+}
+
+// resolverEntry is a cached SRV lookup.
+type resolverEntry struct {
+	records []*net.SRV
+	expiry  time.Time
+}
+
+// NewResolver creates a new Resolver caching SRV records for the provided TTL.
+func NewResolver(ttl time.Duration) *Resolver {
+	return NewDNSResolver(net.DefaultResolver, ttl)
+}
+
+// NewDNSResolver creates a new Resolver looking up SRV records using the provided net.Resolver, such as one
+// querying a specific DNS server, and caching them for the provided TTL.
+func NewDNSResolver(resolver *net.Resolver, ttl time.Duration) *Resolver {
+	return &Resolver{
+		resolver: resolver,
+		ttl:      ttl,
+		cache:    make(map[string]resolverEntry),
+	}
+}
+
+// Resolve resolves the provided address into an address that can be dialed.
+func (r *Resolver) Resolve(ctx context.Context, addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+		if net.ParseIP(host) != nil {
+			return "", fmt.Errorf("missing port in address %v", addr)
+		}
+
+		records, err := r.LookupSRV(ctx, host)
+		if err != nil {
+			return "", err
+		}
+		record := selectSRV(records)
+		return net.JoinHostPort(strings.TrimSuffix(record.Target, "."), fmt.Sprint(record.Port)), nil
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		return net.JoinHostPort(ip.String(), port), nil
+	}
+	return addr, nil
+}
+
+// LookupSRV returns the _spectrum._tcp SRV records of the provided host, using cached records if available.
+func (r *Resolver) LookupSRV(ctx context.Context, host string) ([]*net.SRV, error) {
+	r.mu.Lock()
+	entry, ok := r.cache[host]
+	r.mu.Unlock()
+	if ok && time.Now().Before(entry.expiry) {
+		return entry.records, nil
+	}
+
+	_, records, err := r.resolver.LookupSRV(ctx, "spectrum", "tcp", host)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no srv records found for %v", host)
+	}
+
+	r.mu.Lock()
+	r.cache[host] = resolverEntry{records: records, expiry: time.Now().Add(r.ttl)}
+	r.mu.Unlock()
+	return records, nil
+}
+
+// selectSRV selects a record from the highest priority (lowest value) records, randomly weighted by their weight.
+func selectSRV(records []*net.SRV) *net.SRV {
+	priority := records[0].Priority
+	var total int
+	for _, record := range records {
+		if record.Priority < priority {
+			priority = record.Priority
+		}
+	}
+
+	candidates := make([]*net.SRV, 0, len(records))
+	for _, record := range records {
+		if record.Priority == priority {
+			candidates = append(candidates, record)
+			total += int(record.Weight)
+		}
+	}
+
+	if total == 0 {
+		return candidates[rand.IntN(len(candidates))]
+	}
+
+	n := rand.IntN(total)
+	for _, record := range candidates {
+		n -= int(record.Weight)
+		if n < 0 {
+			return record
+		}
+	}
+	return candidates[len(candidates)-1]
+}
+
+// Resolving wraps a Transport, resolving addresses using a Resolver before dialing them.
+type Resolving struct {
+	transport Transport
+	resolver  *Resolver
+}
+
+// NewResolving creates a new Resolving transport dialing resolved addresses using the provided transport.
+func NewResolving(transport Transport, resolver *Resolver) *Resolving {
+	return &Resolving{
+		transport: transport,
+		resolver:  resolver,
+	}
+}
+
+// Dial ...
+func (r *Resolving) Dial(ctx context.Context, addr string) (io.ReadWriteCloser, error) {
+	resolved, err := r.resolver.Resolve(ctx, addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %v: %w", addr, err)
+	}
+	return r.transport.Dial(ctx, resolved)
+}
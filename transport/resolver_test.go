@@ -0,0 +1,97 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"testing"
+	"time"
+)
+
+// newTestResolver returns a Resolver holding the SRV records in its cache until the expiry, which fails every
+// other lookup.
+func newTestResolver(records map[string][]*net.SRV, expiry time.Time) *Resolver {
+	r := NewResolver(time.Minute)
+	r.resolver = &net.Resolver{
+		PreferGo: true,
+		Dial: func(context.Context, string, string) (net.Conn, error) {
+			return nil, errors.New("lookups are disabled")
+		},
+	}
+	for host, records := range records {
+		r.cache[host] = resolverEntry{records: records, expiry: expiry}
+	}
+	return r
+}
+
+func TestResolverResolve(t *testing.T) {
+	records := map[string][]*net.SRV{
+		"play.example.com": {{Target: "backend.example.com.", Port: 19133}},
+	}
+	tests := []struct {
+		name    string
+		addr    string
+		expiry  time.Duration
+		want    string
+		wantErr bool
+	}{
+		{name: "host and port", addr: "backend.example.com:19132", want: "backend.example.com:19132"},
+		{name: "ipv4", addr: "127.0.0.1:19132", want: "127.0.0.1:19132"},
+		{name: "ipv6", addr: "[::1]:19132", want: "[::1]:19132"},
+		{name: "ipv6 normalised", addr: "[0:0:0:0:0:0:0:1]:19132", want: "[::1]:19132"},
+		{name: "ipv6 without port", addr: "[::1]", wantErr: true},
+		{name: "srv", addr: "play.example.com", expiry: time.Minute, want: "backend.example.com:19133"},
+		{name: "srv expired", addr: "play.example.com", expiry: -time.Minute, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestResolver(records, time.Now().Add(tt.expiry))
+			got, err := r.Resolve(context.Background(), tt.addr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Resolve() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("Resolve() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectSRV(t *testing.T) {
+	tests := []struct {
+		name    string
+		records []*net.SRV
+		want    []string
+	}{
+		{
+			name:    "single",
+			records: []*net.SRV{{Target: "a", Priority: 10}},
+			want:    []string{"a"},
+		},
+		{
+			name:    "lowest priority",
+			records: []*net.SRV{{Target: "a", Priority: 20, Weight: 100}, {Target: "b", Priority: 10}},
+			want:    []string{"b"},
+		},
+		{
+			name:    "weighted",
+			records: []*net.SRV{{Target: "a", Priority: 10, Weight: 1}, {Target: "b", Priority: 10}, {Target: "c", Priority: 20, Weight: 1}},
+			want:    []string{"a"},
+		},
+		{
+			name:    "unweighted",
+			records: []*net.SRV{{Target: "a", Priority: 10}, {Target: "b", Priority: 10}, {Target: "c", Priority: 20}},
+			want:    []string{"a", "b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for range 100 {
+				if record := selectSRV(tt.records); !slices.Contains(tt.want, record.Target) {
+					t.Fatalf("selectSRV() = %v, want one of %v", record.Target, tt.want)
+				}
+			}
+		})
+	}
+}
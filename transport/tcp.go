@@ -0,0 +1,28 @@
+package transport
+
+import (
+	"context"
+	"io"
+	"net"
+)
+
+// TCP implements the Transport interface to establish connections to servers over TCP.
+// Each connection is a separate TCP connection, with Nagle's algorithm disabled to avoid delaying packets.
+type TCP struct {
+	dialer net.Dialer
+}
+
+// NewTCP creates a new TCP transport instance.
+func NewTCP() *TCP {
+	return &TCP{}
+}
+
+// Dial ...
+func (t *TCP) Dial(ctx context.Context, addr string) (io.ReadWriteCloser, error) {
+	conn, err := t.dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	_ = conn.(*net.TCPConn).SetNoDelay(true)
+	return conn, nil
+}
@@ -0,0 +1,94 @@
+package transport
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// Throttle wraps the connection, limiting the rate at which bytes are read from and written to it to the provided
+// rates in bytes per second. A rate of 0 or below leaves the direction unlimited. Bursts of up to a second worth
+// of bytes are allowed, after which reads and writes are delayed until the rate is respected again.
+func Throttle(conn io.ReadWriteCloser, readRate, writeRate int) io.ReadWriteCloser {
+	return &throttledConn{
+		conn:   conn,
+		read:   newTokenBucket(readRate),
+		write:  newTokenBucket(writeRate),
+		closed: make(chan struct{}),
+	}
+}
+
+// throttledConn is a connection rate limited using a token bucket per direction.
+type throttledConn struct {
+	conn   io.ReadWriteCloser
+	read   *tokenBucket
+	write  *tokenBucket
+	closed chan struct{}
+	once   sync.Once
+}
+
+// Read ...
+func (c *throttledConn) Read(p []byte) (n int, err error) {
+	n, err = c.conn.Read(p)
+	c.wait(c.read, n)
+	return n, err
+}
+
+// Write ...
+func (c *throttledConn) Write(p []byte) (n int, err error) {
+	c.wait(c.write, len(p))
+	return c.conn.Write(p)
+}
+
+// Close ...
+func (c *throttledConn) Close() error {
+	c.once.Do(func() {
+		close(c.closed)
+	})
+	return c.conn.Close()
+}
+
+// wait takes n tokens from the bucket, blocking until the bucket is no longer in debt or the connection is closed.
+func (c *throttledConn) wait(bucket *tokenBucket, n int) {
+	if bucket == nil || n <= 0 {
+		return
+	}
+
+	if delay := bucket.take(n); delay > 0 {
+		select {
+		case <-c.closed:
+		case <-time.After(delay):
+		}
+	}
+}
+
+// tokenBucket is a token bucket refilled at a fixed rate, holding at most a second worth of tokens.
+type tokenBucket struct {
+	rate   float64
+	tokens float64
+	last   time.Time
+	mu     sync.Mutex
+}
+
+// newTokenBucket creates a full token bucket refilled at the provided rate per second, or returns nil if the
+// rate is 0 or below.
+func newTokenBucket(rate int) *tokenBucket {
+	if rate <= 0 {
+		return nil
+	}
+	return &tokenBucket{rate: float64(rate), tokens: float64(rate), last: time.Now()}
+}
+
+// take takes n tokens from the bucket, returning the time to wait until the bucket is no longer in debt.
+func (b *tokenBucket) take(n int) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	b.tokens = min(b.rate, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	b.tokens -= float64(n)
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
@@ -0,0 +1,63 @@
+package transport
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenBucketTake(t *testing.T) {
+	// The tolerance accounts for the tokens refilled while the test runs.
+	const tolerance = time.Millisecond * 20
+	tests := []struct {
+		name       string
+		rate       int
+		idle       time.Duration
+		empty      bool
+		takes      []int
+		wantDelays []time.Duration
+	}{
+		{name: "within burst", rate: 1000, takes: []int{500, 500}, wantDelays: []time.Duration{0, 0}},
+		{name: "burst exceeded", rate: 1000, takes: []int{1000, 500}, wantDelays: []time.Duration{0, time.Millisecond * 500}},
+		{name: "debt accumulated", rate: 1000, takes: []int{1500, 1000}, wantDelays: []time.Duration{time.Millisecond * 500, time.Millisecond * 1500}},
+		{name: "refilled", rate: 1000, idle: time.Millisecond * 500, empty: true, takes: []int{500, 500}, wantDelays: []time.Duration{0, time.Millisecond * 500}},
+		{name: "refill capped", rate: 1000, idle: time.Minute, takes: []int{1000, 1000}, wantDelays: []time.Duration{0, time.Second}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newTokenBucket(tt.rate)
+			b.last = time.Now().Add(-tt.idle)
+			if tt.empty {
+				b.tokens = 0
+			}
+			for i, n := range tt.takes {
+				if delay := b.take(n); delay < tt.wantDelays[i]-tolerance || delay > tt.wantDelays[i] {
+					t.Fatalf("take(%v) = %v, want %v", n, delay, tt.wantDelays[i])
+				}
+			}
+		})
+	}
+}
+
+func TestNewTokenBucketUnlimited(t *testing.T) {
+	for _, rate := range []int{0, -1} {
+		if b := newTokenBucket(rate); b != nil {
+			t.Fatalf("newTokenBucket(%v) = %+v, want nil", rate, b)
+		}
+	}
+}
+
+func TestThrottleClose(t *testing.T) {
+	conn := Throttle(&testConn{}, 0, 100)
+	go func() {
+		time.Sleep(time.Millisecond * 50)
+		_ = conn.Close()
+	}()
+
+	start := time.Now()
+	if _, err := conn.Write(make([]byte, 1000)); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Write() blocked for %v after the connection was closed", elapsed)
+	}
+}
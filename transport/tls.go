@@ -0,0 +1,25 @@
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+)
+
+// TLS implements the Transport interface to establish connections to servers over TCP secured using TLS.
+// Providing client certificates in its configuration enables mutual TLS, authenticating the proxy to servers
+// which require it, while the configured root CAs are used to verify the servers.
+type TLS struct {
+	dialer tls.Dialer
+}
+
+// NewTLS creates a new TLS transport instance using the provided TLS configuration. The configuration is cloned
+// for every connection, and its ServerName is derived from the dialed address if empty.
+func NewTLS(config *tls.Config) *TLS {
+	return &TLS{dialer: tls.Dialer{Config: config}}
+}
+
+// Dial ...
+func (t *TLS) Dial(ctx context.Context, addr string) (io.ReadWriteCloser, error) {
+	return t.dialer.DialContext(ctx, "tcp", addr)
+}
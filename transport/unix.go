@@ -0,0 +1,24 @@
+package transport
+
+import (
+	"context"
+	"io"
+	"net"
+	"strings"
+)
+
+// Unix implements the Transport interface to establish connections to servers running on the same host over
+// unix domain sockets. Addresses are paths to the servers' sockets, optionally prefixed with unix://.
+type Unix struct {
+	dialer net.Dialer
+}
+
+// NewUnix creates a new Unix transport instance.
+func NewUnix() *Unix {
+	return &Unix{}
+}
+
+// Dial ...
+func (u *Unix) Dial(ctx context.Context, addr string) (io.ReadWriteCloser, error) {
+	return u.dialer.DialContext(ctx, "unix", strings.TrimPrefix(addr, "unix://"))
+}
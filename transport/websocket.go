@@ -0,0 +1,57 @@
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/websocket"
+)
+
+// WebSocket implements the Transport interface to establish connections to servers over WebSocket.
+// Addresses may either be ws:// or wss:// URLs, or plain host:port addresses which are dialed as wss:// URLs
+// if the transport has a TLS configuration and as ws:// URLs otherwise.
+// Each connection is a separate WebSocket, with packets exchanged as binary frames.
+type WebSocket struct {
+	header    http.Header
+	tlsConfig *tls.Config
+}
+
+// NewWebSocket creates a new WebSocket transport instance. The provided header, which may be nil, is sent along with
+// every handshake, and the TLS configuration, which may also be nil, is used for wss:// addresses.
+func NewWebSocket(header http.Header, tlsConfig *tls.Config) *WebSocket {
+	return &WebSocket{
+		header:    header,
+		tlsConfig: tlsConfig,
+	}
+}
+
+// Dial ...
+func (w *WebSocket) Dial(ctx context.Context, addr string) (io.ReadWriteCloser, error) {
+	if !strings.HasPrefix(addr, "ws://") && !strings.HasPrefix(addr, "wss://") {
+		if w.tlsConfig != nil {
+			addr = "wss://" + addr
+		} else {
+			addr = "ws://" + addr
+		}
+	}
+
+	origin := "http" + strings.TrimPrefix(addr, "ws")
+	config, err := websocket.NewConfig(addr, origin)
+	if err != nil {
+		return nil, err
+	}
+
+	if w.header != nil {
+		config.Header = w.header.Clone()
+	}
+	config.TlsConfig = w.tlsConfig
+	conn, err := config.DialContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	conn.PayloadType = websocket.BinaryFrame
+	return conn, nil
+}
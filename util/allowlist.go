@@ -0,0 +1,246 @@
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sandertv/gophertunnel/minecraft"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrNotAllowlisted is returned by Allowlist.LoginFilter for players that are not allowlisted.
+var ErrNotAllowlisted = errors.New("you are not allowlisted on this server")
+
+// AllowlistEntries holds the players allowed by an Allowlist, as stored in its file in either YAML or JSON.
+type AllowlistEntries struct {
+	// XUIDs are the XUIDs of the allowed players.
+	XUIDs []string `yaml:"xuids" json:"xuids"`
+	// Names are the names of the allowed players, compared case-insensitively.
+	Names []string `yaml:"names" json:"names"`
+}
+
+// Allowlist restricts the players allowed to log in to the proxy by their XUID or name. It is checked during
+// login by setting Opts.LoginFilter to Allowlist.LoginFilter. An Allowlist loaded from a file is checked for
+// changes at an interval, and entries added or removed at runtime are written back to the file.
+type Allowlist struct {
+	path   string
+	logger *slog.Logger
+
+	xuids   map[string]struct{}
+	names   map[string]struct{}
+	modTime time.Time
+	mu      sync.RWMutex
+
+	cancelFunc context.CancelFunc
+	ctx        context.Context
+}
+
+// NewAllowlist creates a new Allowlist holding the provided entries in memory.
+func NewAllowlist(entries AllowlistEntries) *Allowlist {
+	a := &Allowlist{}
+	a.ctx, a.cancelFunc = context.WithCancel(context.Background())
+	a.set(entries)
+	return a
+}
+
+// LoadAllowlist creates a new Allowlist reading its entries from the file at the provided path, checking the file
+// for changes at the given interval until it is closed. An error is returned if the interval is not positive or
+// if the file could not be read initially.
+func LoadAllowlist(path string, logger *slog.Logger, interval time.Duration) (*Allowlist, error) {
+	if interval <= 0 {
+		return nil, errors.New("interval must be positive")
+	}
+
+	a := &Allowlist{path: path, logger: logger}
+	if _, err := a.Reload(); err != nil {
+		return nil, err
+	}
+	a.ctx, a.cancelFunc = context.WithCancel(context.Background())
+	go a.watch(interval)
+	return a, nil
+}
+
+// LoginFilter returns ErrNotAllowlisted if the player of the connection is not allowlisted. It may be set as
+// Opts.LoginFilter.
+func (a *Allowlist) LoginFilter(conn *minecraft.Conn) error {
+	identityData := conn.IdentityData()
+	if !a.Allowed(identityData.XUID, identityData.DisplayName) {
+		return ErrNotAllowlisted
+	}
+	return nil
+}
+
+// Allowed reports whether the player with the XUID or name is allowlisted.
+func (a *Allowlist) Allowed(xuid string, name string) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if _, ok := a.xuids[xuid]; ok && xuid != "" {
+		return true
+	}
+	_, ok := a.names[strings.ToLower(name)]
+	return ok
+}
+
+// Entries returns the entries of the allowlist.
+func (a *Allowlist) Entries() AllowlistEntries {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.entries()
+}
+
+// AddXUID allows the player with the XUID, saving the allowlist to its file if it has one.
+func (a *Allowlist) AddXUID(xuid string) error {
+	return a.update(func() { a.xuids[xuid] = struct{}{} })
+}
+
+// RemoveXUID disallows the player with the XUID, saving the allowlist to its file if it has one.
+func (a *Allowlist) RemoveXUID(xuid string) error {
+	return a.update(func() { delete(a.xuids, xuid) })
+}
+
+// AddName allows the player with the name, saving the allowlist to its file if it has one.
+func (a *Allowlist) AddName(name string) error {
+	return a.update(func() { a.names[strings.ToLower(name)] = struct{}{} })
+}
+
+// RemoveName disallows the player with the name, saving the allowlist to its file if it has one.
+func (a *Allowlist) RemoveName(name string) error {
+	return a.update(func() { delete(a.names, strings.ToLower(name)) })
+}
+
+// Reload reads the file of the allowlist if it was modified since it was last read, reporting whether the
+// entries were replaced. If the file is invalid, the entries it last contained are kept.
+func (a *Allowlist) Reload() (bool, error) {
+	if a.path == "" {
+		return false, nil
+	}
+
+	info, err := os.Stat(a.path)
+	if err != nil {
+		return false, fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	a.mu.RLock()
+	modTime := a.modTime
+	a.mu.RUnlock()
+	if info.ModTime().Equal(modTime) {
+		return false, nil
+	}
+
+	data, err := os.ReadFile(a.path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	// YAML is a superset of JSON, so both are decoded by the YAML decoder.
+	var entries AllowlistEntries
+	if err := yaml.Unmarshal(data, &entries); err != nil {
+		a.mu.Lock()
+		a.modTime = info.ModTime()
+		a.mu.Unlock()
+		return false, fmt.Errorf("failed to decode file: %w", err)
+	}
+
+	a.mu.Lock()
+	a.set(entries)
+	a.modTime = info.ModTime()
+	a.mu.Unlock()
+	return true, nil
+}
+
+// Close stops watching the file of the allowlist for changes.
+func (a *Allowlist) Close() error {
+	a.cancelFunc()
+	return nil
+}
+
+// watch reloads the file at every interval until the allowlist is closed.
+func (a *Allowlist) watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-ticker.C:
+			if reloaded, err := a.Reload(); err != nil {
+				a.logger.Error("failed to reload allowlist", "path", a.path, "err", err)
+			} else if reloaded {
+				a.logger.Info("reloaded allowlist", "path", a.path)
+			}
+		}
+	}
+}
+
+// update applies the change to the entries and saves them to the file of the allowlist, if it has one.
+func (a *Allowlist) update(change func()) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	change()
+	if a.path == "" {
+		return nil
+	}
+
+	entries := a.entries()
+	var (
+		data []byte
+		err  error
+	)
+	if strings.EqualFold(filepath.Ext(a.path), ".json") {
+		data, err = json.MarshalIndent(entries, "", "\t")
+	} else {
+		data, err = yaml.Marshal(entries)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode allowlist: %w", err)
+	}
+
+	if err := os.WriteFile(a.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	// The file written is not read back by the next reload.
+	if info, err := os.Stat(a.path); err == nil {
+		a.modTime = info.ModTime()
+	}
+	return nil
+}
+
+// set replaces the entries of the allowlist.
+func (a *Allowlist) set(entries AllowlistEntries) {
+	a.xuids = make(map[string]struct{}, len(entries.XUIDs))
+	for _, xuid := range entries.XUIDs {
+		a.xuids[xuid] = struct{}{}
+	}
+
+	a.names = make(map[string]struct{}, len(entries.Names))
+	for _, name := range entries.Names {
+		a.names[strings.ToLower(name)] = struct{}{}
+	}
+}
+
+// entries returns the entries of the allowlist, sorted to keep its file stable.
+func (a *Allowlist) entries() AllowlistEntries {
+	entries := AllowlistEntries{
+		XUIDs: make([]string, 0, len(a.xuids)),
+		Names: make([]string, 0, len(a.names)),
+	}
+	for xuid := range a.xuids {
+		entries.XUIDs = append(entries.XUIDs, xuid)
+	}
+	for name := range a.names {
+		entries.Names = append(entries.Names, name)
+	}
+	slices.Sort(entries.XUIDs)
+	slices.Sort(entries.Names)
+	return entries
+}
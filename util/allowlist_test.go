@@ -0,0 +1,134 @@
+package util
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newTestAllowlist returns an Allowlist loaded from a file with the name and content, which is closed once the
+// test finishes.
+func newTestAllowlist(t *testing.T, name string, content string) (*Allowlist, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	a, err := LoadAllowlist(path, slog.New(slog.NewTextHandler(io.Discard, nil)), time.Hour)
+	if err != nil {
+		t.Fatalf("LoadAllowlist() error = %v", err)
+	}
+	t.Cleanup(func() { _ = a.Close() })
+	return a, path
+}
+
+func TestLoadAllowlist(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		missing  bool
+		interval time.Duration
+		wantErr  bool
+	}{
+		{name: "valid", content: "names: [Steve]", interval: time.Minute},
+		{name: "missing file", missing: true, interval: time.Minute, wantErr: true},
+		{name: "invalid file", content: "names: a: b", interval: time.Minute, wantErr: true},
+		{name: "zero interval", content: "names: [Steve]", wantErr: true},
+		{name: "negative interval", content: "names: [Steve]", interval: -time.Minute, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "allowlist.yml")
+			if !tt.missing {
+				if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+					t.Fatalf("WriteFile() error = %v", err)
+				}
+			}
+			a, err := LoadAllowlist(path, slog.New(slog.NewTextHandler(io.Discard, nil)), tt.interval)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadAllowlist() error = %v, want error %v", err, tt.wantErr)
+			}
+			if a != nil {
+				_ = a.Close()
+			}
+		})
+	}
+}
+
+func TestAllowlistAllowed(t *testing.T) {
+	a := NewAllowlist(AllowlistEntries{XUIDs: []string{"2535400000000000"}, Names: []string{"Steve"}})
+	tests := []struct {
+		name   string
+		xuid   string
+		player string
+		want   bool
+	}{
+		{name: "xuid", xuid: "2535400000000000", player: "Alex", want: true},
+		{name: "name", player: "Steve", want: true},
+		{name: "name ignoring case", player: "sTEVE", want: true},
+		{name: "not allowlisted", xuid: "2535400000000001", player: "Alex"},
+		{name: "name prefix", player: "Ste"},
+		{name: "no xuid", player: "Alex"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := a.Allowed(tt.xuid, tt.player); got != tt.want {
+				t.Fatalf("Allowed(%q, %q) = %v, want %v", tt.xuid, tt.player, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllowlistUpdate(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{name: "yaml", file: "allowlist.yml", content: "xuids: [\"1\"]\nnames: [Steve]"},
+		{name: "json", file: "allowlist.json", content: `{"xuids": ["1"], "names": ["Steve"]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, path := newTestAllowlist(t, tt.file, tt.content)
+			for _, update := range []func() error{
+				func() error { return a.AddXUID("2") },
+				func() error { return a.RemoveXUID("1") },
+				func() error { return a.AddName("Alex") },
+				func() error { return a.RemoveName("STEVE") },
+			} {
+				if err := update(); err != nil {
+					t.Fatalf("update error = %v", err)
+				}
+			}
+
+			want := AllowlistEntries{XUIDs: []string{"2"}, Names: []string{"alex"}}
+			if got := a.Entries(); !reflect.DeepEqual(got, want) {
+				t.Fatalf("Entries() = %+v, want %+v", got, want)
+			}
+			if reloaded, err := a.Reload(); reloaded || err != nil {
+				t.Fatalf("Reload() = %v, %v, want the file written not to be read back", reloaded, err)
+			}
+
+			// The entries written to the file are read by a new allowlist.
+			loaded, _ := newTestAllowlist(t, tt.file, readFile(t, path))
+			if got := loaded.Entries(); !reflect.DeepEqual(got, want) {
+				t.Fatalf("Entries() of the file written = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+// readFile returns the content of the file at the path.
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	return string(data)
+}
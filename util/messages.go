@@ -0,0 +1,75 @@
+package util
+
+import "strings"
+
+const (
+	// MessageDiscoveryFailed is the key of the message players are disconnected with when no server could be
+	// discovered for them during login.
+	MessageDiscoveryFailed = "discovery_failed"
+	// MessageDialFailed is the key of the message players are disconnected with when the server discovered for
+	// them during login could not be connected to.
+	MessageDialFailed = "dial_failed"
+	// MessageInvalidGameData is the key of the message players are disconnected with when the server discovered for
+	// them during login sent game data that failed validation.
+	MessageInvalidGameData = "invalid_game_data"
+	// MessageSpawnFailed is the key of the message players are disconnected with when they could not be spawned in
+	// the server discovered for them during login, after its connection sequence completed.
+	MessageSpawnFailed = "spawn_failed"
+	// MessageLoginTimeout is the key of the message players are disconnected with when their login, including
+	// the connection sequence with the server and their spawn, did not complete in time.
+	MessageLoginTimeout = "login_timeout"
+	// MessageFallbackFailed is the key of the message players are disconnected with when their server
+	// connection was lost and they could not be transferred to a fallback server.
+	MessageFallbackFailed = "fallback_failed"
+	// MessageLoggedInElsewhere is the key of the message players are disconnected with when they log in again
+	// from another location.
+	MessageLoggedInElsewhere = "logged_in_elsewhere"
+	// MessageProxyFull is the key of the message players are disconnected with when they log in while the maximum
+	// amount of sessions set by Opts.MaxSessions are online.
+	MessageProxyFull = "proxy_full"
+)
+
+// Messages holds the templates of the messages players are disconnected with by the proxy, keyed by message keys
+// such as MessageDialFailed. Templates may contain the {server} and {error} placeholders, replaced with the address
+// of the server involved and the error that caused the disconnection. Players are disconnected with the error
+// itself for messages without a template.
+type Messages struct {
+	// Templates are the default templates of the messages.
+	Templates map[string]string `yaml:"templates"`
+	// Locales holds templates overriding the default templates for players using a specific language, keyed by
+	// language codes such as "en_GB" or "en". Exact language codes take precedence over bare languages.
+	Locales map[string]map[string]string `yaml:"locales"`
+}
+
+// Format formats the message with the key for a player with the language code, such as "en_GB", reporting false
+// if the message has no template.
+func (m Messages) Format(languageCode string, key string, server string, err string) (string, bool) {
+	template, ok := m.template(languageCode, key)
+	if !ok {
+		return "", false
+	}
+	return strings.NewReplacer("{server}", server, "{error}", err).Replace(template), true
+}
+
+// template returns the template of the message with the key for the language code.
+func (m Messages) template(languageCode string, key string) (string, bool) {
+	for locale, templates := range m.Locales {
+		if strings.EqualFold(locale, languageCode) {
+			if template, ok := templates[key]; ok {
+				return template, true
+			}
+		}
+	}
+
+	language, _, _ := strings.Cut(languageCode, "_")
+	for locale, templates := range m.Locales {
+		if strings.EqualFold(locale, language) {
+			if template, ok := templates[key]; ok {
+				return template, true
+			}
+		}
+	}
+
+	template, ok := m.Templates[key]
+	return template, ok
+}
@@ -1,31 +1,201 @@
 package util
 
+import (
+	"github.com/sandertv/gophertunnel/minecraft"
+	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
+)
+
+const (
+	// DuplicateLoginPolicyKickOld closes the existing session of a player logging in again.
+	DuplicateLoginPolicyKickOld = "kick_old"
+	// DuplicateLoginPolicyRejectNew rejects the login of a player who already has a session.
+	DuplicateLoginPolicyRejectNew = "reject_new"
+)
+
+const (
+	// UnknownClientPacketPolicyForwardRaw forwards unknown client packets to the server without decoding them.
+	UnknownClientPacketPolicyForwardRaw = "forward_raw"
+	// UnknownClientPacketPolicyDrop silently drops unknown client packets.
+	UnknownClientPacketPolicyDrop = "drop"
+	// UnknownClientPacketPolicyDisconnect disconnects clients sending unknown packets.
+	UnknownClientPacketPolicyDisconnect = "disconnect"
+)
+
 // Opts defines the configuration options for Spectrum.
 type Opts struct {
 	// Addr is the address to listen on.
 	Addr string `yaml:"addr"`
 	// AutoLogin determines whether automatic login should be enabled.
 	AutoLogin bool `yaml:"auto_login"`
-	// ClientDecode is a list of client packet identifiers that need to be decoded by the proxy.
+	// ClientDecode is a list of client packet identifiers that need to be decoded by the proxy. Listed packets are
+	// still forwarded without being decoded if no processor of the session processes them, unless they have to be
+	// converted to the protocol used with servers.
 	ClientDecode []uint32 `yaml:"client_decode"`
+	// ConnectionBanDuration is the duration in milliseconds for which IP addresses exceeding ConnectionRateLimit are
+	// refused. A duration of 0 only refuses the connections exceeding the limit.
+	ConnectionBanDuration int64 `yaml:"connection_ban_duration"`
+	// ConnectionRateLimit is the maximum amount of connections accepted from a single IP address within
+	// ConnectionRateWindow. Connections exceeding it are closed before their login handshake starts. A limit of 0
+	// leaves it unlimited.
+	ConnectionRateLimit int `yaml:"connection_rate_limit"`
+	// ConnectionRateWindow is the window in milliseconds in which connections are counted for ConnectionRateLimit.
+	ConnectionRateWindow int64 `yaml:"connection_rate_window"`
+	// DialAttempts is the maximum amount of attempts made to dial a server during a login or transfer. Once all of
+	// them fail, a session.DialError is returned. A value of 0 or 1 dials servers only once.
+	DialAttempts int `yaml:"dial_attempts"`
+	// DialBackoff is the delay in milliseconds before retrying a failed dial, doubled after every retry.
+	DialBackoff int64 `yaml:"dial_backoff"`
+	// DialBackoffMax is the maximum delay in milliseconds between two dial attempts. A value of 0 leaves it unlimited.
+	DialBackoffMax int64 `yaml:"dial_backoff_max"`
+	// DialJitter is the fraction of the backoff, between 0 and 1, randomly added to every delay between dial attempts.
+	DialJitter float64 `yaml:"dial_jitter"`
+	// DialTimeout is the maximum time in milliseconds spent on a single attempt to dial a server.
+	// A timeout of 0 only limits dials by the timeout of the login or transfer itself.
+	DialTimeout int64 `yaml:"dial_timeout"`
+	// DuplicateLoginPolicy determines what happens when a player logs in while a session with the same XUID
+	// exists, either DuplicateLoginPolicyKickOld or DuplicateLoginPolicyRejectNew. DuplicateLoginPolicyKickOld is
+	// used if it is empty or unknown.
+	DuplicateLoginPolicy string `yaml:"duplicate_login_policy"`
+	// EntityDataFlags is a list of additional flags, such as protocol.EntityDataFlagInvisible, set on the player's
+	// entity by the proxy on top of the flags it always sets, whenever it sends the player's metadata during
+	// transfers. Actor data sent by servers for the player is not modified.
+	EntityDataFlags []uint8 `yaml:"entity_data_flags"`
+	// FallbackAttempts is the maximum amount of attempts made to transfer a player to a fallback server, discovered
+	// using server.Discovery.DiscoverFallback, once their server connection is lost unexpectedly. The player is
+	// disconnected once all of them fail. A value of 0 makes a single attempt, and a negative value disconnects the
+	// player without falling back.
+	FallbackAttempts int `yaml:"fallback_attempts"`
+	// FallbackRetryDelay is the delay in milliseconds between two attempts to transfer a player to a fallback server.
+	FallbackRetryDelay int64 `yaml:"fallback_retry_delay"`
+	// InterceptServerTransfer determines whether packet.Transfer packets sent by servers should be intercepted.
+	// When enabled, the proxy transfers the player to the packet's address internally instead of forwarding
+	// the packet to the client, which would otherwise disconnect the client from the proxy.
+	InterceptServerTransfer bool `yaml:"intercept_server_transfer"`
 	// LatencyInterval is the interval at which the latency of the connection is updated in milliseconds.
 	// Lower intervals provide more accurate latency but use more bandwidth.
 	LatencyInterval int64 `yaml:"latency_interval"`
+	// LimboOnNoBackend determines whether players are held in an empty world served by the proxy when no server
+	// could be discovered or connected to for them during login, or when their server connection is lost and no
+	// fallback server is available, rather than being disconnected. Players held in limbo are transferred to a
+	// server as soon as one is discovered.
+	LimboOnNoBackend bool `yaml:"limbo_on_no_backend"`
+	// LimboMessage is the message sent to players once they are held in limbo. No message is sent if it is empty.
+	LimboMessage string `yaml:"limbo_message"`
+	// LimboTitle is the title displayed to players while they are held in limbo, such as "Reconnecting...".
+	// No title is displayed if it is empty.
+	LimboTitle string `yaml:"limbo_title"`
+	// LimboRetryInterval is the interval at which discovery is retried for players held in limbo in milliseconds.
+	// Intervals below 1000 milliseconds, including 0, are raised to 1000 milliseconds.
+	LimboRetryInterval int64 `yaml:"limbo_retry_interval"`
+	// LoginFallbackAttempts is the maximum amount of fallback servers, discovered using
+	// server.Discovery.DiscoverFallback, tried during login when the connection to the discovered server could
+	// not be established. A value of 0 disconnects the player as soon as the first connection fails.
+	LoginFallbackAttempts int `yaml:"login_fallback_attempts"`
+	// LoginFilter is consulted at the start of the login sequence, before a server is discovered or dialed.
+	// Returning an error rejects the login, disconnecting the client with the error's message. Allowlist.LoginFilter
+	// may be used to only allow allowlisted players to log in.
+	LoginFilter func(conn *minecraft.Conn) error `yaml:"-"`
+	// MaxBackendPacketErrors is the number of malformed packets tolerated from a single server connection.
+	// Malformed packets below this threshold are logged and skipped, while exceeding it closes the connection.
+	MaxBackendPacketErrors int `yaml:"max_backend_packet_errors"`
+	// MaxHandshakes is the maximum amount of connections whose login handshake is in progress at once. Connections
+	// exceeding it are closed before their handshake starts. A value of 0 leaves it unlimited.
+	MaxHandshakes int `yaml:"max_handshakes"`
+	// MaxSessions is the maximum amount of sessions online at once, counting the ones logging in. Players logging
+	// in once it is reached are disconnected with the MessageProxyFull message before a server is discovered for them, unless
+	// MaxSessionsExempt exempts them. A value of 0 leaves it unlimited.
+	MaxSessions int `yaml:"max_sessions"`
+	// MaxSessionsExempt, if set, reports whether the player of the connection may log in even though MaxSessions
+	// sessions are online, such as for staff members.
+	MaxSessionsExempt func(conn *minecraft.Conn) bool `yaml:"-"`
+	// Messages holds the templates of the messages players are disconnected with when the proxy fails to
+	// connect them to a server, such as when discovery fails or the server could not be dialed.
+	Messages Messages `yaml:"messages"`
+	// RelayServerDisconnect determines whether a packet.Disconnect sent by a server closes the session with the
+	// server's message. By default, the packet is not forwarded to the client, and the session falls back once the
+	// server closes the connection, as it does when the connection to the server is lost.
+	RelayServerDisconnect bool `yaml:"relay_server_disconnect"`
+	// RemapEntityIDs determines whether the entity IDs of the player are translated between the IDs assigned by
+	// the server the player logged in to, which the client keeps using, and the ones assigned by the server they
+	// were transferred to, allowing servers to assign IDs independently. The IDs are rewritten in the packets
+	// known to carry them, which are decoded for this purpose even when forwarded without requiring decoding.
+	RemapEntityIDs bool `yaml:"remap_entity_ids"`
+	// ServerPool holds additional packets decoded by the proxy when read from servers, on top of the packets of
+	// the protocol in use. It allows custom server packets to be decoded instead of being treated as unknown.
+	ServerPool packet.Pool `yaml:"-"`
+	// ServerReadRate is the maximum rate in bytes per second at which data is read from a session's server.
+	// A rate of 0 leaves it unlimited.
+	ServerReadRate int `yaml:"server_read_rate"`
+	// ServerResourcePacks returns the UUIDs of the resource packs required by the server at the provided address.
+	// Transfers to servers requiring packs that were not sent to the client at login are refused with a
+	// session.ResourcePackMismatchError, as clients cannot download packs without reconnecting. Resource packs
+	// are not checked if nil.
+	ServerResourcePacks func(addr string) []string `yaml:"-"`
+	// ServerWriteRate is the maximum rate in bytes per second at which data is written to a session's server.
+	// A rate of 0 leaves it unlimited.
+	ServerWriteRate int `yaml:"server_write_rate"`
 	// ShutdownMessage is the message displayed to clients when Spectrum shuts down.
 	ShutdownMessage string `yaml:"shutdown_message"`
+	// ShutdownTransferAddr is the address of another proxy players are transferred to when Spectrum is shut down
+	// gracefully using Spectrum.Shutdown, such as "play.example.com:19132". Players are disconnected with
+	// ShutdownMessage instead if it is empty.
+	ShutdownTransferAddr string `yaml:"shutdown_transfer_addr"`
 	// SyncProtocol determines the protocol version the proxy should use when communicating with servers.
 	// When enabled, the proxy uses the client's protocol version (minecraft.Protocol) for reading and
 	// writing packets. If disabled, the proxy defaults to using the latest protocol version (minecraft.DefaultProtocol).
 	SyncProtocol bool `yaml:"sync_protocol"`
+	// TransferCooldown is the minimum interval in milliseconds between two transfers of a session. Transfers
+	// requested sooner are refused with session.ErrTransferCooldown, except for the ones initiated by the proxy
+	// itself, such as fallbacks. A cooldown of 0 disables it.
+	TransferCooldown int64 `yaml:"transfer_cooldown"`
+	// TransferLoopThreshold is the maximum amount of transfers to the same server allowed within TransferLoopWindow.
+	// Transfers exceeding it are refused with session.ErrTransferLoop. A threshold of 0 disables loop detection.
+	TransferLoopThreshold int `yaml:"transfer_loop_threshold"`
+	// TransferLoopWindow is the window in milliseconds in which transfers are counted for loop detection.
+	TransferLoopWindow int64 `yaml:"transfer_loop_window"`
+	// TransferLoopMessage is the message sent to the player when a transfer is refused due to a transfer loop.
+	// The player remains on their current server, and no message is sent if it is empty.
+	TransferLoopMessage string `yaml:"transfer_loop_message"`
+	// TransferTitle is the title displayed to the player while a transfer is in progress. The title is shown
+	// once the transfer starts and cleared once it completes or fails. No title is shown if both TransferTitle
+	// and TransferSubtitle are empty.
+	TransferTitle string `yaml:"transfer_title"`
+	// TransferSubtitle is the subtitle displayed to the player alongside TransferTitle while a transfer is in progress.
+	TransferSubtitle string `yaml:"transfer_subtitle"`
+	// TransferChunkProvider provides the chunks sent to the player around their new position during a transfer,
+	// allowing a custom loading world to be displayed. It returns the raw payload of the packet.LevelChunk at
+	// the given chunk position along with the amount of sub chunks it contains. Empty chunks are sent if nil.
+	TransferChunkProvider func(dimension, x, z int32) (payload []byte, subChunkCount uint32) `yaml:"-"`
+	// TransferMoveMode is the packet.MovePlayer mode used to reposition the player after a transfer.
+	// Some clients rubber-band the player towards their previous position when packet.MoveModeReset is used,
+	// which is avoided by using packet.MoveModeTeleport.
+	TransferMoveMode byte `yaml:"transfer_move_mode"`
+	// UnknownClientPacketPolicy determines how packets listed in ClientDecode are handled if the proxy does not
+	// know how to decode them, one of UnknownClientPacketPolicyForwardRaw, UnknownClientPacketPolicyDrop or
+	// UnknownClientPacketPolicyDisconnect. If empty, the packet is treated as a failure to write to the server,
+	// closing the server connection.
+	UnknownClientPacketPolicy string `yaml:"unknown_client_packet_policy"`
 }
 
 // DefaultOpts returns the default configuration options for Spectrum.
 func DefaultOpts() *Opts {
 	return &Opts{
-		Addr:            ":19132",
-		AutoLogin:       true,
-		LatencyInterval: 3000,
-		ShutdownMessage: "Spectrum closed.",
-		SyncProtocol:    false,
+		Addr:                   ":19132",
+		AutoLogin:              true,
+		ConnectionRateWindow:   10_000,
+		DialAttempts:           1,
+		DialBackoff:            500,
+		DialBackoffMax:         5000,
+		DialJitter:             0.2,
+		DialTimeout:            10_000,
+		DuplicateLoginPolicy:   DuplicateLoginPolicyKickOld,
+		FallbackAttempts:       1,
+		FallbackRetryDelay:     1000,
+		LatencyInterval:        3000,
+		LimboRetryInterval:     5000,
+		MaxBackendPacketErrors: 5,
+		ShutdownMessage:        "Spectrum closed.",
+		SyncProtocol:           false,
+		TransferMoveMode:       packet.MoveModeTeleport,
 	}
 }